@@ -6,57 +6,66 @@ import (
 	"io"
 	"math/big"
 	"strings"
+
+	"github.com/marcopeereboom/gck/diagnostics"
 )
 
 // operations
 const (
-	Uminus       = 65000
-	Lt           = 65001
-	Gt           = 65002
-	Le           = 65003
-	Ge           = 65004
-	Ne           = 65005
-	Eq           = 65006
-	Assign       = 65007
-	Add          = 65008
-	Sub          = 65009
-	Mul          = 65010
-	Div          = 65011
-	Eos          = 65020
-	While        = 65030
-	If           = 65031
-	Function     = 65032
-	FunctionCall = 65033
-	NeedStart    = 65100 // hint for the backend to create start location
-	Done         = 65101
-	Program      = 65102
+	Uminus    = 65000
+	Lt        = 65001
+	Gt        = 65002
+	Le        = 65003
+	Ge        = 65004
+	Ne        = 65005
+	Eq        = 65006
+	Assign    = 65007
+	Add       = 65008
+	Sub       = 65009
+	Mul       = 65010
+	Div       = 65011
+	Len       = 65012
+	And       = 65013
+	Or        = 65014
+	Not       = 65015
+	Eos       = 65020
+	List      = 65021 // flat list of nodes, e.g. parameters
+	NeedStart = 65100 // hint for the backend to create start location
+	Done      = 65101
+	Program   = 65102
 )
 
 var (
 	ops = map[int]string{
-		Uminus:       "-",
-		Lt:           "<",
-		Gt:           ">",
-		Le:           "<=",
-		Ge:           ">=",
-		Ne:           "!=",
-		Eq:           "==",
-		Assign:       "=",
-		Add:          "+",
-		Sub:          "-",
-		Mul:          "*",
-		Div:          "/",
-		Eos:          "EOS",
-		While:        "while",
-		If:           "if",
-		Function:     "func",
-		FunctionCall: "call func",
-		NeedStart:    "NEED START",
-		Done:         "DONE",
-		Program:      "PROG",
+		Uminus:    "-",
+		Lt:        "<",
+		Gt:        ">",
+		Le:        "<=",
+		Ge:        ">=",
+		Ne:        "!=",
+		Eq:        "==",
+		Assign:    "=",
+		Add:       "+",
+		Sub:       "-",
+		Mul:       "*",
+		Div:       "/",
+		Len:       "len",
+		And:       "&&",
+		Or:        "||",
+		Not:       "!",
+		Eos:       "EOS",
+		List:      "list",
+		NeedStart: "NEED START",
+		Done:      "DONE",
+		Program:   "PROG",
 	}
 )
 
+// OpString returns the source representation of operation op, e.g. + for Add.
+func OpString(op int) string {
+	return ops[op]
+}
+
 // pseudo opcodes
 const (
 	IDENTIFIER = 0
@@ -75,15 +84,20 @@ const (
 	NEEDSTART  = 13
 	DONE       = 14
 	PROGRAM    = 15
+	DISCARD    = 16
+	STRING     = 17
+	BOOLEAN    = 18
+	CALL       = 19
 )
 
 // NodeDebugInformation contains debug information that can be extracted by
 // the backend etc for examination.
 type NodeDebugInformation struct {
-	LineNo   int    // Line number
-	ColStart int    // Token column start on line
-	ColEnd   int    // Token column end on line
-	Line     string // Raw line text
+	LineNo   int    `json:"lineNo"`            // Line number
+	ColStart int    `json:"colStart"`          // Token column start on line
+	ColEnd   int    `json:"colEnd"`            // Token column end on line
+	Line     string `json:"line"`              // Raw line text
+	LineEnd  int    `json:"lineEnd,omitempty"` // Last line, 0 if LineNo
 }
 
 // NodeIdentifier contains a string identifier.
@@ -137,6 +151,40 @@ func NewNumber(d *NodeDebugInformation, num *big.Rat) Node {
 	}
 }
 
+// NodeString contains a string.
+type NodeString struct {
+	Value string
+}
+
+// NewString returns an initialized NodeString structure.
+func NewString(d *NodeDebugInformation, s string) Node {
+	ns := NodeString{
+		Value: s,
+	}
+
+	return Node{
+		Debug: d,
+		Value: ns,
+	}
+}
+
+// NodeBool contains a boolean.
+type NodeBool struct {
+	Value bool
+}
+
+// NewBool returns an initialized NodeBool structure.
+func NewBool(d *NodeDebugInformation, b bool) Node {
+	nb := NodeBool{
+		Value: b,
+	}
+
+	return Node{
+		Debug: d,
+		Value: nb,
+	}
+}
+
 // NodeIdentifier contains an operand (such as + - ; etc) and its associated
 // leaf nodes.
 type NodeOperand struct {
@@ -162,6 +210,17 @@ func NewOperand(d *NodeDebugInformation, operand int, args ...Node) Node {
 	return n
 }
 
+// Append returns list with nodes appended to its leafs.
+// The list node must be a NodeOperand.
+func Append(list Node, nodes ...Node) Node {
+	o := list.Value.(NodeOperand)
+	o.Nodes = append(o.Nodes, nodes...)
+	return Node{
+		Debug: list.Debug,
+		Value: o,
+	}
+}
+
 // Node is the genric container type for all other nodes and is the "currency"
 // that is passed around.
 type Node struct {
@@ -170,10 +229,11 @@ type Node struct {
 }
 
 // DumpPseudoAsm dumps human readable pseudo assembler to w.
-func DumpPseudoAsm(n Node, w io.Writer) error {
+// Calls to externs are emitted as calls into the target environment.
+func DumpPseudoAsm(n Node, w io.Writer, externs ...Extern) error {
 	a := astResult{}
 	a.ec = a.emitPseudoAsm
-	err := a.dumpCode(n, w)
+	err := a.dumpCode(n, w, externs)
 	return err
 }
 
@@ -185,13 +245,58 @@ func DumpAST(n Node, w io.Writer) error {
 
 // EmitCode dumps a binary image to w.
 // This code should be executable by the target architecture.
-func EmitCode(n Node, w io.Writer, f func(int, ...interface{}) error) error {
+// Calls to externs are emitted as calls into the target environment.
+func EmitCode(n Node, w io.Writer, f func(int, ...interface{}) error,
+	externs ...Extern) error {
+
 	a := astResult{}
 	a.ec = f
-	err := a.dumpCode(n, w)
+	err := a.dumpCode(n, w, externs)
 	return err
 }
 
+// label returns a short description of node value value.
+// Children are not part of the description with the exception of the
+// parameters and results of functions.
+func label(value interface{}) string {
+	switch v := value.(type) {
+	case NodeOperand:
+		return ops[v.Operand]
+	case UnaryExpr:
+		return ops[v.Op]
+	case BinaryExpr:
+		return ops[v.Op]
+	case AssignStmt:
+		return ops[Assign]
+	case DiscardStmt:
+		return "discard"
+	case IfNode:
+		return "if"
+	case WhileNode:
+		return "while"
+	case FuncDecl:
+		return fmt.Sprintf("func %v(%v)(%v)", v.Name, names(v.Params),
+			names(v.Results))
+	case CallExpr:
+		return "call " + v.Name
+	case GlobalDecl:
+		return "global"
+	case NodeString:
+		return fmt.Sprintf("%q", v.Value)
+	case NodeIdentifier:
+		return v.Value
+	case NodeInteger:
+		return fmt.Sprintf("%v", v.Value)
+	case NodeNumber:
+		return fmt.Sprintf("%v", v.Value)
+	case NodeBool:
+		return fmt.Sprintf("%v", v.Value)
+	case Node:
+		return label(v.Value)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
 func prettyPrint(value interface{}, indent string) string {
 	var s string
 	switch v := value.(type) {
@@ -209,18 +314,47 @@ func prettyPrint(value interface{}, indent string) string {
 		for _, vv := range v.Nodes {
 			s += prettyPrint(vv, indent)
 		}
+	case UnaryExpr, BinaryExpr, AssignStmt, DiscardStmt, IfNode, WhileNode,
+		FuncDecl, CallExpr, GlobalDecl:
+		c := children(Node{Value: value})
+		if v, ok := value.(FuncDecl); ok {
+			c = []Node{v.Body}
+		}
+		l := label(value)
+		s += fmt.Sprintf("%v%v \\\n", indent, l)
+		indent += strings.Repeat(" ", len(l)+2) + "| "
+		for _, vv := range c {
+			s += prettyPrint(vv, indent)
+		}
 	case NodeInteger:
 		s += fmt.Sprintf("%v%v\n", indent, v.Value)
+	case NodeNumber:
+		s += fmt.Sprintf("%v%v\n", indent, v.Value)
 	case Node:
 		s += prettyPrint(v.Value, indent)
 	case NodeIdentifier:
 		s += fmt.Sprintf("%v%v\n", indent, v.Value)
+	case NodeString:
+		s += fmt.Sprintf("%v%q\n", indent, v.Value)
+	case NodeBool:
+		s += fmt.Sprintf("%v%v\n", indent, v.Value)
 	default:
 		s += fmt.Sprintf("skip %T\n", value)
 	}
 	return s
 }
 
+// names returns the comma separated values of identifiers l.
+func names(l []Node) string {
+	s := make([]string, 0, len(l))
+	for _, v := range l {
+		if id, ok := v.Value.(NodeIdentifier); ok {
+			s = append(s, id.Value)
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
 func (n Node) String() string {
 	if n.Value == nil {
 		return ""
@@ -228,8 +362,9 @@ func (n Node) String() string {
 	return prettyPrint(n.Value, "")
 }
 
-// Clone AST.
-// BUG Pointers on debug and leafs are reused for now; that is bad!
+// Clone returns a deep copy of AST n.
+// Nothing is shared between n and the copy, including debug information and
+// numbers, so the copy can be modified without affecting n.
 func Clone(n Node) Node {
 	r := Node{}
 
@@ -238,15 +373,102 @@ func Clone(n Node) Node {
 		r.Debug = &debug
 	}
 
-	switch nn := n.Value.(type) {
-	case Node:
-		r.Value = Clone(nn)
-	default:
+	if nn, ok := n.Value.(NodeNumber); ok {
+		if nn.Value != nil {
+			nn.Value = new(big.Rat).Set(nn.Value)
+		}
+		r.Value = nn
+		return r
+	}
+
+	c := children(n)
+	if c == nil {
+		// remaining leaves are values
 		r.Value = n.Value
+		return r
+	}
+	cc := make([]Node, 0, len(c))
+	for _, v := range c {
+		cc = append(cc, Clone(v))
 	}
+	r.Value = withChildren(n, cc).Value
 	return r
 }
 
+// Equal returns true if ASTs a and b have the same shape and values.
+// Debug information is ignored so that trees can be compared before and after
+// a transformation.
+func Equal(a, b Node) bool {
+	if an, ok := a.Value.(NodeNumber); ok {
+		bn, ok := b.Value.(NodeNumber)
+		if !ok {
+			return false
+		}
+		if an.Value == nil || bn.Value == nil {
+			return an.Value == bn.Value
+		}
+		return an.Value.Cmp(bn.Value) == 0
+	}
+
+	ac, bc := children(a), children(b)
+	if ac == nil && bc == nil {
+		// remaining leaves are comparable values
+		return a.Value == b.Value
+	}
+	if !sameKind(a, b) || len(ac) != len(bc) {
+		return false
+	}
+	for k := range ac {
+		if !Equal(ac[k], bc[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKind returns true if a and b are the same node type and have identical
+// non-node fields.
+func sameKind(a, b Node) bool {
+	switch av := a.Value.(type) {
+	case Node:
+		_, ok := b.Value.(Node)
+		return ok
+	case NodeOperand:
+		bv, ok := b.Value.(NodeOperand)
+		return ok && av.Operand == bv.Operand
+	case UnaryExpr:
+		bv, ok := b.Value.(UnaryExpr)
+		return ok && av.Op == bv.Op
+	case BinaryExpr:
+		bv, ok := b.Value.(BinaryExpr)
+		return ok && av.Op == bv.Op
+	case AssignStmt:
+		_, ok := b.Value.(AssignStmt)
+		return ok
+	case DiscardStmt:
+		_, ok := b.Value.(DiscardStmt)
+		return ok
+	case IfNode:
+		_, ok := b.Value.(IfNode)
+		return ok
+	case WhileNode:
+		_, ok := b.Value.(WhileNode)
+		return ok
+	case FuncDecl:
+		bv, ok := b.Value.(FuncDecl)
+		return ok && av.Name == bv.Name &&
+			len(av.Params) == len(bv.Params) &&
+			len(av.Results) == len(bv.Results)
+	case CallExpr:
+		bv, ok := b.Value.(CallExpr)
+		return ok && av.Name == bv.Name
+	case GlobalDecl:
+		_, ok := b.Value.(GlobalDecl)
+		return ok
+	}
+	return false
+}
+
 func ExtraDebug(n Node) string {
 	if n.Debug == nil {
 		return ""
@@ -257,3 +479,24 @@ func ExtraDebug(n Node) string {
 		n.Debug.ColStart,
 		n.Debug.ColEnd)
 }
+
+// Pos returns the source location of d.
+// The location is unknown if d is nil.
+func (d *NodeDebugInformation) Pos() diagnostics.Pos {
+	if d == nil {
+		return diagnostics.Pos{}
+	}
+	return diagnostics.Pos{
+		LineNo:   d.LineNo,
+		ColStart: d.ColStart,
+		ColEnd:   d.ColEnd,
+		Line:     d.Line,
+	}
+}
+
+// Errorf returns an error diagnostic at the location of node n.
+func Errorf(n Node, code diagnostics.Code, format string,
+	args ...interface{}) error {
+
+	return diagnostics.Errorf(n.Debug.Pos(), code, format, args...)
+}
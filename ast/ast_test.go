@@ -0,0 +1,211 @@
+package ast
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+func testTree() Node {
+	d := &NodeDebugInformation{LineNo: 1, Line: "a = 1.5 + b;"}
+	return NewAssign(d, []Node{NewIdentifier(d, "a")},
+		NewBinary(d, Add,
+			NewNumber(d, big.NewRat(3, 2)),
+			NewIdentifier(d, "b")))
+}
+
+func TestClone(t *testing.T) {
+	n := testTree()
+	c := Clone(n)
+	if !Equal(n, c) {
+		t.Errorf("clone not equal")
+		return
+	}
+
+	// modify everything that could be shared
+	c.Debug.LineNo = 2
+	assign := c.Value.(AssignStmt)
+	assign.Value.Value.(BinaryExpr).X.Value.(NodeNumber).Value.SetInt64(7)
+	assign.Targets[0] = NewString(nil, "moo")
+
+	if n.Debug.LineNo != 1 {
+		t.Errorf("debug information shared")
+	}
+	orig := n.Value.(AssignStmt)
+	x := orig.Value.Value.(BinaryExpr).X
+	if x.Value.(NodeNumber).Value.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("number shared")
+	}
+	if _, ok := orig.Targets[0].Value.(NodeIdentifier); !ok {
+		t.Errorf("nodes shared")
+	}
+	if Equal(n, c) {
+		t.Errorf("modified clone equal")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b  Node
+		equal bool
+	}{
+		{NewInteger(nil, 1), NewInteger(nil, 1), true},
+		{NewInteger(nil, 1), NewInteger(nil, 2), false},
+		{NewInteger(nil, 1), NewNumber(nil, big.NewRat(1, 1)), false},
+		{NewNumber(nil, big.NewRat(2, 4)), NewNumber(nil, big.NewRat(1, 2)),
+			true},
+		{NewString(nil, "a"), NewIdentifier(nil, "a"), false},
+		{NewBool(nil, true), NewBool(nil, true), true},
+		{NewOperand(nil, Eos), NewOperand(nil, Eos, NewInteger(nil, 1)),
+			false},
+		{NewBinary(nil, Add, NewInteger(nil, 1), NewInteger(nil, 2)),
+			NewBinary(nil, Sub, NewInteger(nil, 1), NewInteger(nil, 2)),
+			false},
+		{NewIf(nil, NewBool(nil, true), NewOperand(nil, Eos), Node{}),
+			NewIf(nil, NewBool(nil, true), NewOperand(nil, Eos),
+				NewOperand(nil, Eos)), false},
+		{NewCall(nil, "f", nil), NewCall(nil, "f", []Node{}), true},
+		{NewCall(nil, "f", nil), NewCall(nil, "g", nil), false},
+		{testTree(), testTree(), true},
+	}
+
+	for k, v := range tests {
+		if Equal(v.a, v.b) != v.equal {
+			t.Errorf("%v: expected %v", k, v.equal)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var ids []string
+	Inspect(testTree(), func(n Node) bool {
+		if id, ok := n.Value.(NodeIdentifier); ok {
+			ids = append(ids, id.Value)
+		}
+		// don't descend into additions
+		_, ok := n.Value.(BinaryExpr)
+		return !ok
+	})
+	if len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("invalid identifiers %v", ids)
+	}
+}
+
+// counter counts nodes and verifies that every Visit is terminated.
+type counter struct {
+	nodes *int
+	depth *int
+}
+
+func (c counter) Visit(n Node) Visitor {
+	if n.Value == nil {
+		*c.depth--
+		return nil
+	}
+	*c.nodes++
+	*c.depth++
+	return c
+}
+
+func TestWalk(t *testing.T) {
+	var nodes, depth int
+	Walk(counter{nodes: &nodes, depth: &depth}, testTree())
+	if nodes != 5 || depth != 0 {
+		t.Errorf("invalid walk %v %v", nodes, depth)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	n := testTree()
+	orig := Clone(n)
+
+	// rename b to c and count order
+	var order []int
+	r := Rewrite(n, func(n Node) Node {
+		switch v := n.Value.(type) {
+		case NodeIdentifier:
+			if v.Value == "b" {
+				return NewIdentifier(n.Debug, "c")
+			}
+		case BinaryExpr:
+			order = append(order, v.Op)
+		case AssignStmt:
+			order = append(order, Assign)
+		}
+		return n
+	})
+
+	if !Equal(n, orig) {
+		t.Errorf("original modified")
+	}
+	want := NewAssign(nil, []Node{NewIdentifier(nil, "a")},
+		NewBinary(nil, Add,
+			NewNumber(nil, big.NewRat(3, 2)),
+			NewIdentifier(nil, "c")))
+	if !Equal(r, want) {
+		t.Errorf("invalid rewrite %v", r)
+	}
+	if len(order) != 2 || order[0] != Add || order[1] != Assign {
+		t.Errorf("not bottom-up %v", order)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	a := NewIdentifier(nil, "a")
+	one := NewInteger(nil, 1)
+	f := NewFunc(nil, "f", nil, nil, NewOperand(nil, Eos))
+	tests := []struct {
+		n     Node
+		valid bool
+	}{
+		{testTree(), true},
+		{NewBinary(nil, Not, one, one), false},
+		{NewUnary(nil, Add, one), false},
+		{NewAssign(nil, nil, one), false},
+		{NewAssign(nil, []Node{one}, one), false},
+		{NewAssign(nil, []Node{a, a}, one), false},
+		{NewAssign(nil, []Node{a, a}, NewCall(nil, "f", nil)), true},
+		{NewAssign(nil, []Node{a}, NewOperand(nil, Eos)), false},
+		{NewIf(nil, one, NewOperand(nil, Eos), Node{}), true},
+		{NewWhile(nil, one, Node{}), false},
+		{NewFunc(nil, "f", []Node{one}, nil, NewOperand(nil, Eos)),
+			false},
+		{NewGlobal(nil, []Node{a}), true},
+		{NewOperand(nil, Add, one, one), false},
+		{NewDiscard(nil, NewCall(nil, "f", []Node{testTree()})), false},
+		{NewIf(nil, one, one, Node{}), false},
+		{NewWhile(nil, one, a), false},
+		{NewFunc(nil, "f", nil, nil, NewCall(nil, "g", nil)), false},
+		{NewOperand(nil, Eos, NewDiscard(nil, one), one), false},
+		{NewOperand(nil, Program, NewOperand(nil, Eos, f, f)), true},
+		{NewFunc(nil, "g", nil, nil, f), false},
+		{NewFunc(nil, "g", nil, nil, NewOperand(nil, Eos, f)), false},
+		{NewOperand(nil, Program, NewWhile(nil, one, f)), false},
+	}
+
+	for k, v := range tests {
+		err := Validate(v.n)
+		if (err == nil) != v.valid {
+			t.Errorf("%v: expected valid %v, got %v", k, v.valid, err)
+		}
+	}
+}
+
+func TestExternArguments(t *testing.T) {
+	e := Extern{Name: "os.print", Params: []string{TypeAny}}
+	n := NewOperand(nil, Program,
+		NewFunc(nil, "main", nil, nil,
+			NewDiscard(nil, NewCall(nil, "os.print",
+				[]Node{NewInteger(nil, 1), NewInteger(nil, 2)}))))
+
+	var w bytes.Buffer
+	err := DumpPseudoAsm(n, &w, e)
+	var d *diagnostics.Diagnostic
+	if !errors.As(err, &d) || d.Code != diagnostics.ArgumentCount ||
+		d.Message != "function os.print expects 1 argument(s), got 2" {
+		t.Fatalf("expected argument count error, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotEscape escapes s for use in a graphviz label.
+// Newlines are replaced by left justified line breaks.
+func dotEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\t", "    ", -1)
+	return strings.Replace(s, "\n", `\l`, -1)
+}
+
+// roles returns the description of the edges between n and its children.
+// The result has the same length as children(n).
+func roles(n Node) []string {
+	c := children(n)
+	r := make([]string, len(c))
+	switch nn := n.Value.(type) {
+	case BinaryExpr:
+		r[0], r[1] = "x", "y"
+	case AssignStmt:
+		r[len(r)-1] = "value"
+	case IfNode:
+		r[0], r[1] = "cond", "then"
+		if len(r) > 2 {
+			r[2] = "else"
+		}
+	case WhileNode:
+		r[0], r[1] = "cond", "body"
+	case FuncDecl:
+		for k := range nn.Params {
+			r[k] = "param"
+		}
+		for k := range nn.Results {
+			r[len(nn.Params)+k] = "result"
+		}
+		r[len(r)-1] = "body"
+	}
+	return r
+}
+
+// DumpASTDot writes AST n as a graphviz digraph to w.
+func DumpASTDot(n Node, w io.Writer) error {
+	var (
+		id  int
+		err error
+	)
+	p := func(f string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, f, args...)
+		}
+	}
+
+	var dump func(n Node) int
+	dump = func(n Node) int {
+		me := id
+		id++
+		line := ""
+		if n.Debug != nil {
+			line = fmt.Sprintf(`\nline %v`, n.Debug.LineNo)
+		}
+		p("\tn%v [label=\"%v%v\"];\n", me, dotEscape(label(n.Value)),
+			line)
+		r := roles(n)
+		for k, v := range children(n) {
+			c := dump(v)
+			p("\tn%v -> n%v [label=\"%v\"];\n", me, c, r[k])
+		}
+		return me
+	}
+
+	p("digraph ast {\n")
+	p("\tnode [shape=box];\n")
+	dump(n)
+	p("}\n")
+
+	return err
+}
+
+// edge is a control flow graph edge.
+type edge struct {
+	to    string
+	label string
+	call  bool // edge to a function that is called
+}
+
+// block is a basic block in a control flow graph.
+type block struct {
+	name  string
+	code  []string
+	edges []edge
+}
+
+// cfg creates a control flow graph from pseudo opcodes.
+// Blocks start at every location and after every branch; they end at
+// branches, jumps and returns.
+type cfg struct {
+	asm    astResult // used to render pseudo asm
+	blocks []*block
+	cur    *block // block that is being filled, nil after a branch
+
+	fall      *block // block that falls through into the next block
+	fallLabel string // label of the fall through edge
+}
+
+// label returns the block name of pseudo asm location l.
+// Numbered locations, which branches refer to as l<number>, are named
+// .L<number> so that they can't collide with function names.
+func (c *cfg) label(l interface{}) string {
+	if i, ok := l.(int); ok {
+		return fmt.Sprintf(".L%v", i)
+	}
+	return fmt.Sprintf("%v", l)
+}
+
+// start begins a new block named name and links a block that falls through
+// to it.
+// Blocks without a location are named .B<number>.
+func (c *cfg) start(name string) {
+	if name == "" {
+		name = fmt.Sprintf(".B%v", len(c.blocks))
+	}
+	b := &block{name: name}
+	if c.fall != nil {
+		c.fall.edges = append(c.fall.edges, edge{
+			to:    name,
+			label: c.fallLabel,
+		})
+	}
+	c.blocks = append(c.blocks, b)
+	c.cur, c.fall, c.fallLabel = b, nil, ""
+}
+
+// ec records pseudo opcode t with arguments args.
+// It has the same signature as the EmitCode callback.
+func (c *cfg) ec(t int, args ...interface{}) error {
+	switch t {
+	case DEBUG, FIXUP, PROGRAM, DONE, NEEDSTART:
+		return nil
+	case LOCATION:
+		if c.cur != nil {
+			c.fall = c.cur
+		}
+		c.start(c.label(args[0]))
+		return nil
+	}
+
+	if c.cur == nil {
+		// unreachable code or the start of the program
+		c.start("")
+	}
+	l := len(c.asm.code)
+	err := c.asm.emitPseudoAsm(t, args...)
+	if err != nil {
+		return err
+	}
+	c.cur.code = append(c.cur.code, c.asm.code[l:]...)
+
+	switch t {
+	case BRT, BRF:
+		taken, notTaken := "true", "false"
+		if t == BRF {
+			taken, notTaken = notTaken, taken
+		}
+		c.cur.edges = append(c.cur.edges, edge{
+			to:    c.label(args[0]),
+			label: taken,
+		})
+		c.fall, c.fallLabel, c.cur = c.cur, notTaken, nil
+	case JUMP:
+		c.cur.edges = append(c.cur.edges, edge{to: c.label(args[0])})
+		c.cur = nil
+	case RETURN, EXIT:
+		c.cur = nil
+	case JSR:
+		c.cur.edges = append(c.cur.edges, edge{
+			to:   c.label(args[0]),
+			call: true,
+		})
+	}
+
+	return nil
+}
+
+// DumpCFGDot writes the control flow graph of AST n as a graphviz digraph to
+// w.
+// Every node is a basic block of pseudo asm; calls are drawn as dashed edges.
+// See DumpPseudoAsm for the meaning of externs.
+func DumpCFGDot(n Node, w io.Writer, externs ...Extern) error {
+	c := cfg{}
+	a := astResult{ec: c.ec}
+	err := a.generate(n, externs)
+	if err != nil {
+		return err
+	}
+
+	p := func(f string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, f, args...)
+		}
+	}
+	p("digraph cfg {\n")
+	p("\tnode [shape=box fontname=monospace];\n")
+	for _, b := range c.blocks {
+		p("\t%q [label=\"%v:\\l%v\"];\n", b.name, dotEscape(b.name),
+			dotEscape(strings.Join(b.code, "")))
+		for _, e := range b.edges {
+			style := ""
+			if e.call {
+				style = " style=dashed"
+			}
+			p("\t%q -> %q [label=\"%v\"%v];\n", b.name, e.to, e.label,
+				style)
+		}
+	}
+	p("}\n")
+
+	return err
+}
@@ -0,0 +1,89 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCFG(t *testing.T) {
+	// if a < 1 { b = 1; } else { b = 2; }
+	a, b := NewIdentifier(nil, "a"), NewIdentifier(nil, "b")
+	n := NewIf(nil, NewBinary(nil, Lt, a, NewInteger(nil, 1)),
+		NewAssign(nil, []Node{b}, NewInteger(nil, 1)),
+		NewAssign(nil, []Node{b}, NewInteger(nil, 2)))
+
+	c := cfg{}
+	s := astResult{ec: c.ec}
+	err := s.generate(n, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// condition, then, else and the join block
+	want := map[string][]edge{
+		".B0": {{to: ".L0", label: "false"}, {to: ".B1", label: "true"}},
+		".B1": {{to: ".L1"}},
+		".L0": {{to: ".L1"}},
+		".L1": nil,
+	}
+	if len(c.blocks) != len(want) {
+		t.Errorf("invalid number of blocks %v", len(c.blocks))
+		return
+	}
+	for _, v := range c.blocks {
+		edges, found := want[v.name]
+		if !found || len(edges) != len(v.edges) {
+			t.Errorf("invalid block %v %v", v.name, v.edges)
+			continue
+		}
+		for k := range edges {
+			if edges[k] != v.edges[k] {
+				t.Errorf("invalid edge %v %v", v.name, v.edges[k])
+			}
+		}
+	}
+
+	var w bytes.Buffer
+	err = DumpCFGDot(n, &w)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(w.String(), `".B0" -> ".L0" [label="false"];`) {
+		t.Errorf("invalid graph %v", w.String())
+	}
+}
+
+func TestCFGNames(t *testing.T) {
+	// functions named like generated blocks remain separate blocks
+	a := NewIdentifier(nil, "a")
+	n := NewOperand(nil, Program,
+		NewFunc(nil, "main", nil, nil,
+			NewWhile(nil, NewBool(nil, false),
+				NewAssign(nil, []Node{a}, NewCall(nil, "l0",
+					nil)))),
+		NewFunc(nil, "l0", nil, []Node{a},
+			NewAssign(nil, []Node{a}, NewInteger(nil, 1))),
+		NewFunc(nil, "b0", nil, nil, NewOperand(nil, Eos)))
+
+	c := cfg{}
+	s := astResult{ec: c.ec}
+	err := s.generate(n, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, v := range c.blocks {
+		if names[v.name] {
+			t.Fatalf("duplicate block %v", v.name)
+		}
+		names[v.name] = true
+	}
+	for _, v := range []string{"main", "l0", "b0", ".L0"} {
+		if !names[v] {
+			t.Fatalf("missing block %v in %v", v, names)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package ast
+
+// Type names that are used in Extern signatures.
+const (
+	TypeAny    = "any"
+	TypeInt    = "int"
+	TypeNumber = "number"
+	TypeString = "string"
+	TypeBool   = "bool"
+)
+
+// Extern describes a function that is provided by the target environment
+// instead of being defined in the source, e.g. os.print.
+// Parameter and result types are one of the Type constants.
+// The arguments of calls are checked against them by semantic.TypeCheck.
+type Extern struct {
+	Name    string
+	Params  []string
+	Results []string
+}
@@ -0,0 +1,359 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Comment is a source comment that was retained by a tokenizer.
+// Text contains the comment including its delimiters.
+type Comment struct {
+	LineNo   int    `json:"lineNo"`
+	ColStart int    `json:"colStart"`
+	ColEnd   int    `json:"colEnd"`
+	Text     string `json:"text"`
+}
+
+// precedence returns the binding strength of an operation.
+// Operands are never wrapped in parenthesis so they bind the strongest.
+func precedence(n Node) int {
+	switch v := n.Value.(type) {
+	case BinaryExpr:
+		switch v.Op {
+		case Or:
+			return 1
+		case And:
+			return 2
+		case Lt, Gt, Le, Ge, Ne, Eq:
+			return 3
+		case Add, Sub:
+			return 4
+		case Mul, Div:
+			return 5
+		}
+	case UnaryExpr:
+		if v.Op != Len {
+			return 6
+		}
+	}
+	return 7
+}
+
+// numberString returns r as a decimal number that tokenizes as a NUMBER.
+func numberString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String() + ".0"
+	}
+
+	// literals are finite decimals so the denominator only contains
+	// factors 2 and 5; anything else is rounded
+	d := new(big.Int).Set(r.Denom())
+	prec := 0
+	for _, f := range []int64{2, 5} {
+		n, m := 0, new(big.Int)
+		bf := big.NewInt(f)
+		for {
+			q := new(big.Int)
+			q.QuoRem(d, bf, m)
+			if m.Sign() != 0 {
+				break
+			}
+			d, n = q, n+1
+		}
+		if n > prec {
+			prec = n
+		}
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		prec = 32
+	}
+	return r.FloatString(prec)
+}
+
+// expression returns the source representation of expression n.
+func expression(n Node) string {
+	switch v := n.Value.(type) {
+	case NodeIdentifier:
+		return v.Value
+	case NodeInteger:
+		return strconv.Itoa(v.Value)
+	case NodeNumber:
+		return numberString(v.Value)
+	case NodeString:
+		return strconv.Quote(v.Value)
+	case NodeBool:
+		return strconv.FormatBool(v.Value)
+	case CallExpr:
+		args := make([]string, 0, len(v.Args))
+		for _, a := range v.Args {
+			args = append(args, expression(a))
+		}
+		return v.Name + "(" + strings.Join(args, ", ") + ")"
+	case UnaryExpr:
+		if v.Op == Len {
+			return "len(" + expression(v.X) + ")"
+		}
+		x := expression(v.X)
+		if precedence(v.X) < precedence(n) ||
+			strings.HasPrefix(x, ops[v.Op]) {
+			x = "(" + x + ")"
+		}
+		return ops[v.Op] + x
+	case BinaryExpr:
+		// operations are left associative
+		x, y := expression(v.X), expression(v.Y)
+		if precedence(v.X) < precedence(n) {
+			x = "(" + x + ")"
+		}
+		if precedence(v.Y) <= precedence(n) {
+			y = "(" + y + ")"
+		}
+		return x + " " + ops[v.Op] + " " + y
+	}
+	return fmt.Sprintf("/* %T */", n.Value)
+}
+
+// statements returns the statements in n.
+// Nested statement lists are flattened and empty statements are dropped.
+func statements(n Node) []Node {
+	o, ok := n.Value.(NodeOperand)
+	if !ok {
+		return []Node{n}
+	}
+	switch o.Operand {
+	case Eos, Program:
+		var s []Node
+		for _, v := range o.Nodes {
+			s = append(s, statements(v)...)
+		}
+		return s
+	}
+	// NeedStart, Done etc
+	return nil
+}
+
+// firstLine returns the first source line of n or 0 if it is unknown.
+func firstLine(n Node) int {
+	line := 0
+	Inspect(n, func(c Node) bool {
+		if c.Debug != nil && c.Debug.LineNo > 0 &&
+			(line == 0 || c.Debug.LineNo < line) {
+			line = c.Debug.LineNo
+		}
+		return true
+	})
+	return line
+}
+
+// lastLine returns the last source line of n or 0 if it is unknown.
+func lastLine(n Node) int {
+	line := endLine(n)
+	for _, c := range children(n) {
+		if l := lastLine(c); l > line {
+			line = l
+		}
+	}
+	return line
+}
+
+// printer reprints an AST as source code.
+type printer struct {
+	b        bytes.Buffer
+	comments []Comment // comments that have not been printed
+	indent   int
+	line     int // last source line that was printed
+	next     int // first source line of the next statement, 0 if unknown
+}
+
+// newline terminates the current line and indents the next one.
+func (p *printer) newline() {
+	p.b.WriteString("\n")
+	p.b.WriteString(strings.Repeat("\t", p.indent))
+}
+
+// flush prints all comments that start before line on lines of their own.
+// A single empty line is retained between source lines that are apart.
+func (p *printer) flush(line int) {
+	for len(p.comments) != 0 && p.comments[0].LineNo < line {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.gap(c.LineNo)
+		p.b.WriteString(c.Text)
+		p.newline()
+		p.line = c.LineNo + strings.Count(c.Text, "\n")
+	}
+}
+
+// blank returns true if nothing or an empty line was printed last.
+func (p *printer) blank() bool {
+	s := strings.TrimRight(p.b.String(), "\t")
+	return s == "" || strings.HasSuffix(s, "\n\n")
+}
+
+// gap prints an empty line if line is not adjacent to the last line that was
+// printed.
+func (p *printer) gap(line int) {
+	if p.line != 0 && line > p.line+1 && !p.blank() {
+		// the empty line is not indented
+		p.b.Truncate(len(strings.TrimRight(p.b.String(), "\t")))
+		p.newline()
+	}
+}
+
+// trailing prints the comments that start on line after the current
+// statement.
+func (p *printer) trailing(line int) {
+	for len(p.comments) != 0 && p.comments[0].LineNo == line {
+		p.b.WriteString(" " + p.comments[0].Text)
+		p.line = line + strings.Count(p.comments[0].Text, "\n")
+		p.comments = p.comments[1:]
+	}
+}
+
+// block prints a braced statement list.
+// Comments that start before end are printed inside the block; set end to 0
+// if the end of the block is not known.
+func (p *printer) block(n Node, end int) {
+	p.b.WriteString("{")
+	p.indent++
+	p.newline()
+	p.list(statements(n))
+	if end != 0 {
+		p.flush(end)
+	}
+	// remove indentation of the closing brace
+	p.b.Truncate(p.b.Len() - 1)
+	p.indent--
+	p.b.WriteString("}")
+}
+
+// list prints statements, each on a line of its own.
+func (p *printer) list(s []Node) {
+	for k, v := range s {
+		first, last := firstLine(v), lastLine(v)
+		if _, ok := v.Value.(FuncDecl); ok && k != 0 && !p.blank() {
+			// functions are always separated by an empty line
+			p.newline()
+		}
+		if first != 0 {
+			pending := len(p.comments)
+			p.flush(first)
+			if k != 0 || pending != len(p.comments) {
+				p.gap(first)
+			}
+			p.line = first
+		}
+		p.next = 0
+		if k+1 < len(s) {
+			p.next = firstLine(s[k+1])
+		}
+		p.statement(v)
+		if last != 0 {
+			p.trailing(last)
+			p.line = last
+		}
+		p.newline()
+	}
+}
+
+// statement prints statement n without a terminating newline.
+func (p *printer) statement(n Node) {
+	switch v := n.Value.(type) {
+	case AssignStmt:
+		p.b.WriteString(names(v.Targets) + " = " + expression(v.Value) +
+			";")
+	case DiscardStmt:
+		p.b.WriteString(expression(v.X) + ";")
+	case GlobalDecl:
+		p.b.WriteString("global " + names(v.Names) + ";")
+	case WhileNode:
+		p.b.WriteString("while " + expression(v.Cond) + " ")
+		p.block(v.Body, endLine(n))
+	case IfNode:
+		p.b.WriteString("if " + expression(v.Cond) + " ")
+		switch v.Else.Value.(type) {
+		case nil:
+			p.block(v.Then, endLine(n))
+		case IfNode:
+			p.block(v.Then, firstLine(v.Else))
+			p.b.WriteString(" else ")
+			p.statement(v.Else)
+		default:
+			// comments between the branches lead the else branch
+			p.block(v.Then, 0)
+			p.b.WriteString(" else ")
+			p.block(v.Else, endLine(n))
+		}
+	case FuncDecl:
+		p.b.WriteString("func " + v.Name + " (" + names(v.Params) +
+			") (" + names(v.Results) + ") ")
+		end := endLine(n)
+		if end == lineNo(n) {
+			end = p.bodyEnd(v.Body)
+		}
+		p.block(v.Body, end)
+	default:
+		p.b.WriteString(expression(n) + ";")
+	}
+}
+
+// bodyEnd returns the line where function body n ends.
+// Functions that only record the line of their header are ended by the first
+// comment that is not indented after the body.
+func (p *printer) bodyEnd(n Node) int {
+	end, last := p.next, lastLine(n)
+	if end == 0 {
+		end = int(^uint(0) >> 1)
+	}
+	for _, c := range p.comments {
+		if c.LineNo >= end {
+			break
+		}
+		if c.LineNo > last && c.ColStart <= 1 {
+			return c.LineNo
+		}
+	}
+	return end
+}
+
+// lineNo returns the line of node n or 0 if it is unknown.
+func lineNo(n Node) int {
+	if n.Debug == nil {
+		return 0
+	}
+	return n.Debug.LineNo
+}
+
+// endLine returns the last line of node n or 0 if it is unknown.
+func endLine(n Node) int {
+	if n.Debug == nil || n.Debug.LineEnd == 0 {
+		return lineNo(n)
+	}
+	return n.Debug.LineEnd
+}
+
+// Format writes AST n as canonical source code to w.
+// Statements are indented with tabs and separated by at most one empty line.
+// Comments are placed on the source lines of the statements they precede or
+// trail.
+func Format(n Node, w io.Writer, comments []Comment) error {
+	p := printer{
+		comments: append([]Comment{}, comments...),
+	}
+	sort.SliceStable(p.comments, func(i, j int) bool {
+		return p.comments[i].LineNo < p.comments[j].LineNo
+	})
+
+	p.list(statements(n))
+	p.b.Truncate(len(strings.TrimRight(p.b.String(), "\t")))
+	p.flush(int(^uint(0) >> 1))
+	s := strings.TrimLeft(p.b.String(), "\n")
+
+	_, err := io.WriteString(w, s)
+	return err
+}
@@ -0,0 +1,77 @@
+package ast
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	l := func(line int) *NodeDebugInformation {
+		return &NodeDebugInformation{LineNo: line}
+	}
+	x := NewIdentifier(nil, "x")
+	n := NewOperand(nil, Program,
+		NewOperand(nil, NeedStart),
+		NewOperand(l(2), Eos,
+			NewAssign(l(2), []Node{x}, NewInteger(l(2), 1)),
+			NewWhile(&NodeDebugInformation{LineNo: 4, LineEnd: 8},
+				NewBinary(l(4), Lt, NewIdentifier(l(4), "x"),
+					NewBinary(l(4), Mul, NewInteger(l(4), 3),
+						NewBinary(l(4), Add,
+							NewNumber(l(4), big.NewRat(1, 2)),
+							NewUnary(l(4), Uminus,
+								NewInteger(l(4), 2))))),
+				NewAssign(l(6), []Node{x},
+					NewBinary(l(6), Sub,
+						NewIdentifier(l(6), "x"),
+						NewBinary(l(6), Sub,
+							NewInteger(l(6), 1),
+							NewString(l(6), "a\n")))))),
+		NewOperand(nil, Done))
+	comments := []Comment{
+		{LineNo: 9, Text: "/* trailer */"},
+		{LineNo: 1, Text: "// header"},
+		{LineNo: 2, Text: "// one"},
+		{LineNo: 5, Text: "// inside"},
+		{LineNo: 7, Text: "// end of loop"},
+	}
+	want := `// header
+x = 1; // one
+
+while x < 3 * (0.5 + -2) {
+	// inside
+	x = x - (1 - "a\n");
+	// end of loop
+}
+/* trailer */
+`
+
+	var w bytes.Buffer
+	err := Format(n, &w, comments)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if w.String() != want {
+		t.Errorf("invalid format:\n%v", w.String())
+	}
+}
+
+func TestNumberString(t *testing.T) {
+	tests := []struct {
+		r    *big.Rat
+		want string
+	}{
+		{big.NewRat(3, 1), "3.0"},
+		{big.NewRat(3, 2), "1.5"},
+		{big.NewRat(1, 40), "0.025"},
+		{big.NewRat(-7, 100), "-0.07"},
+	}
+
+	for _, v := range tests {
+		if got := numberString(v.r); got != v.want {
+			t.Errorf("%v: got %v want %v", v.r, got, v.want)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JSON node kinds.
+const (
+	kindIdentifier = "identifier"
+	kindInteger    = "integer"
+	kindNumber     = "number"
+	kindString     = "string"
+	kindBool       = "bool"
+	kindOperand    = "operand"
+	kindUnary      = "unary"
+	kindBinary     = "binary"
+	kindAssign     = "assign"
+	kindDiscard    = "discard"
+	kindIf         = "if"
+	kindWhile      = "while"
+	kindFunc       = "func"
+	kindCall       = "call"
+	kindGlobal     = "global"
+)
+
+var (
+	// opNames are the JSON names of operations.
+	opNames = map[int]string{
+		Uminus:    "neg",
+		Lt:        "lt",
+		Gt:        "gt",
+		Le:        "le",
+		Ge:        "ge",
+		Ne:        "ne",
+		Eq:        "eq",
+		Add:       "add",
+		Sub:       "sub",
+		Mul:       "mul",
+		Div:       "div",
+		Len:       "len",
+		And:       "and",
+		Or:        "or",
+		Not:       "not",
+		Eos:       "eos",
+		List:      "list",
+		NeedStart: "needstart",
+		Done:      "done",
+		Program:   "program",
+	}
+
+	// opValues is the reverse of opNames.
+	opValues = make(map[string]int)
+)
+
+func init() {
+	for k, v := range opNames {
+		opValues[v] = k
+	}
+}
+
+// jsonNode is the JSON representation of a Node.
+// Value contains the kind specific encoding of the node.
+type jsonNode struct {
+	Kind  string                `json:"kind"`
+	Debug *NodeDebugInformation `json:"debug,omitempty"`
+	Value json.RawMessage       `json:"value"`
+}
+
+type jsonOperand struct {
+	Op    string `json:"op"`
+	Nodes []Node `json:"nodes"`
+}
+
+type jsonUnary struct {
+	Op string `json:"op"`
+	X  Node   `json:"x"`
+}
+
+type jsonBinary struct {
+	Op string `json:"op"`
+	X  Node   `json:"x"`
+	Y  Node   `json:"y"`
+}
+
+type jsonAssign struct {
+	Targets []Node `json:"targets"`
+	Value   Node   `json:"value"`
+}
+
+type jsonIf struct {
+	Cond Node `json:"cond"`
+	Then Node `json:"then"`
+	Else Node `json:"else"`
+}
+
+type jsonWhile struct {
+	Cond Node `json:"cond"`
+	Body Node `json:"body"`
+}
+
+type jsonFunc struct {
+	Name    string `json:"name"`
+	Params  []Node `json:"params"`
+	Results []Node `json:"results"`
+	Body    Node   `json:"body"`
+}
+
+type jsonCall struct {
+	Name string `json:"name"`
+	Args []Node `json:"args"`
+}
+
+// MarshalJSON returns the JSON encoding of AST n.
+func MarshalJSON(n Node) ([]byte, error) {
+	return json.MarshalIndent(n, "", "\t")
+}
+
+// UnmarshalJSON returns the AST that is encoded in JSON document b.
+// The AST is not validated, use Validate for that.
+func UnmarshalJSON(b []byte) (Node, error) {
+	var n Node
+	err := json.Unmarshal(b, &n)
+	return n, err
+}
+
+// opName returns the JSON name of operation op.
+func opName(op int) (string, error) {
+	name, found := opNames[op]
+	if !found {
+		return "", fmt.Errorf("unknown operation %v", op)
+	}
+	return name, nil
+}
+
+// opValue returns the operation of JSON name.
+func opValue(name string) (int, error) {
+	op, found := opValues[name]
+	if !found {
+		return 0, fmt.Errorf("unknown operation %q", name)
+	}
+	return op, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// An empty node is encoded as null.
+func (n Node) MarshalJSON() ([]byte, error) {
+	if n.Value == nil {
+		return []byte("null"), nil
+	}
+
+	var (
+		kind string
+		v    interface{}
+		err  error
+	)
+	switch nn := n.Value.(type) {
+	case NodeIdentifier:
+		kind, v = kindIdentifier, nn.Value
+	case NodeInteger:
+		kind, v = kindInteger, nn.Value
+	case NodeNumber:
+		if nn.Value == nil {
+			return nil, fmt.Errorf("number without value%v",
+				ExtraDebug(n))
+		}
+		kind, v = kindNumber, nn.Value.RatString()
+	case NodeString:
+		kind, v = kindString, nn.Value
+	case NodeBool:
+		kind, v = kindBool, nn.Value
+	case NodeOperand:
+		o := jsonOperand{Nodes: nn.Nodes}
+		o.Op, err = opName(nn.Operand)
+		kind, v = kindOperand, o
+	case UnaryExpr:
+		u := jsonUnary{X: nn.X}
+		u.Op, err = opName(nn.Op)
+		kind, v = kindUnary, u
+	case BinaryExpr:
+		b := jsonBinary{X: nn.X, Y: nn.Y}
+		b.Op, err = opName(nn.Op)
+		kind, v = kindBinary, b
+	case AssignStmt:
+		kind, v = kindAssign, jsonAssign{
+			Targets: nn.Targets,
+			Value:   nn.Value,
+		}
+	case DiscardStmt:
+		kind, v = kindDiscard, nn.X
+	case IfNode:
+		kind, v = kindIf, jsonIf{
+			Cond: nn.Cond,
+			Then: nn.Then,
+			Else: nn.Else,
+		}
+	case WhileNode:
+		kind, v = kindWhile, jsonWhile{
+			Cond: nn.Cond,
+			Body: nn.Body,
+		}
+	case FuncDecl:
+		kind, v = kindFunc, jsonFunc{
+			Name:    nn.Name,
+			Params:  nn.Params,
+			Results: nn.Results,
+			Body:    nn.Body,
+		}
+	case CallExpr:
+		kind, v = kindCall, jsonCall{
+			Name: nn.Name,
+			Args: nn.Args,
+		}
+	case GlobalDecl:
+		kind, v = kindGlobal, nn.Names
+	case Node:
+		// collapse wrapped nodes
+		return nn.MarshalJSON()
+	default:
+		return nil, fmt.Errorf("unknown node type %T%v", n.Value,
+			ExtraDebug(n))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v%v", err, ExtraDebug(n))
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{
+		Kind:  kind,
+		Debug: n.Debug,
+		Value: value,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// A null is decoded as an empty node.
+func (n *Node) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*n = Node{}
+		return nil
+	}
+
+	var jn jsonNode
+	err := json.Unmarshal(b, &jn)
+	if err != nil {
+		return err
+	}
+
+	r := Node{Debug: jn.Debug}
+	switch jn.Kind {
+	case kindIdentifier:
+		var v NodeIdentifier
+		err = json.Unmarshal(jn.Value, &v.Value)
+		r.Value = v
+	case kindInteger:
+		var v NodeInteger
+		err = json.Unmarshal(jn.Value, &v.Value)
+		r.Value = v
+	case kindNumber:
+		var s string
+		err = json.Unmarshal(jn.Value, &s)
+		if err != nil {
+			break
+		}
+		v, ok := new(big.Rat).SetString(s)
+		if !ok {
+			err = fmt.Errorf("invalid number %q", s)
+			break
+		}
+		r.Value = NodeNumber{Value: v}
+	case kindString:
+		var v NodeString
+		err = json.Unmarshal(jn.Value, &v.Value)
+		r.Value = v
+	case kindBool:
+		var v NodeBool
+		err = json.Unmarshal(jn.Value, &v.Value)
+		r.Value = v
+	case kindOperand:
+		var v jsonOperand
+		err = json.Unmarshal(jn.Value, &v)
+		if err != nil {
+			break
+		}
+		o := NodeOperand{Nodes: v.Nodes}
+		o.Operand, err = opValue(v.Op)
+		r.Value = o
+	case kindUnary:
+		var v jsonUnary
+		err = json.Unmarshal(jn.Value, &v)
+		if err != nil {
+			break
+		}
+		u := UnaryExpr{X: v.X}
+		u.Op, err = opValue(v.Op)
+		r.Value = u
+	case kindBinary:
+		var v jsonBinary
+		err = json.Unmarshal(jn.Value, &v)
+		if err != nil {
+			break
+		}
+		bi := BinaryExpr{X: v.X, Y: v.Y}
+		bi.Op, err = opValue(v.Op)
+		r.Value = bi
+	case kindAssign:
+		var v jsonAssign
+		err = json.Unmarshal(jn.Value, &v)
+		r.Value = AssignStmt{Targets: v.Targets, Value: v.Value}
+	case kindDiscard:
+		var v DiscardStmt
+		err = json.Unmarshal(jn.Value, &v.X)
+		r.Value = v
+	case kindIf:
+		var v jsonIf
+		err = json.Unmarshal(jn.Value, &v)
+		r.Value = IfNode{Cond: v.Cond, Then: v.Then, Else: v.Else}
+	case kindWhile:
+		var v jsonWhile
+		err = json.Unmarshal(jn.Value, &v)
+		r.Value = WhileNode{Cond: v.Cond, Body: v.Body}
+	case kindFunc:
+		var v jsonFunc
+		err = json.Unmarshal(jn.Value, &v)
+		r.Value = FuncDecl{
+			Name:    v.Name,
+			Params:  v.Params,
+			Results: v.Results,
+			Body:    v.Body,
+		}
+	case kindCall:
+		var v jsonCall
+		err = json.Unmarshal(jn.Value, &v)
+		r.Value = CallExpr{Name: v.Name, Args: v.Args}
+	case kindGlobal:
+		var v GlobalDecl
+		err = json.Unmarshal(jn.Value, &v.Names)
+		r.Value = v
+	default:
+		err = fmt.Errorf("unknown node kind %q", jn.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("%v%v", err, ExtraDebug(r))
+	}
+
+	*n = r
+	return nil
+}
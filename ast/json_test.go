@@ -0,0 +1,100 @@
+package ast
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+func TestJSON(t *testing.T) {
+	d := &NodeDebugInformation{LineNo: 3, ColStart: 1, ColEnd: 4,
+		Line: "x = 1;"}
+	x := NewIdentifier(d, "x")
+	body := NewOperand(d, Eos,
+		NewGlobal(d, []Node{NewIdentifier(nil, "g")}),
+		NewAssign(d, []Node{x, NewIdentifier(nil, "y")},
+			NewCall(d, "f", []Node{NewString(nil, "s")})),
+		NewIf(d, NewBinary(d, And, NewBool(nil, true),
+			NewUnary(d, Not, NewBool(nil, false))),
+			NewDiscard(d, NewCall(d, "os.print", []Node{x})),
+			Node{}),
+		NewIf(d, NewBinary(d, Lt, NewInteger(nil, 1),
+			NewUnary(d, Len, NewString(nil, "abc"))),
+			NewOperand(d, Eos), NewOperand(d, Eos)),
+		NewWhile(d, NewBool(nil, false), NewOperand(d, Eos)),
+		testTree())
+	n := NewOperand(nil, Program,
+		NewOperand(nil, NeedStart),
+		NewFunc(d, "main", nil, nil, body),
+		NewFunc(d, "f", []Node{NewIdentifier(nil, "a")},
+			[]Node{NewIdentifier(nil, "b"), NewIdentifier(nil, "c")},
+			NewAssign(d, []Node{NewIdentifier(nil, "b")},
+				NewUnary(d, Uminus,
+					NewNumber(nil, big.NewRat(-7, 3))))),
+		NewOperand(nil, Done))
+
+	j, err := MarshalJSON(n)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r, err := UnmarshalJSON(j)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !Equal(n, r) {
+		t.Errorf("round trip not equal:\n%s", j)
+		return
+	}
+
+	// Equal ignores debug information
+	a := r.Value.(NodeOperand).Nodes[1].Value.(FuncDecl).Body
+	if a.Debug == nil || *a.Debug != *d {
+		t.Errorf("invalid debug information %v", a.Debug)
+	}
+	if r.String() != n.String() {
+		t.Errorf("invalid AST %v", r)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	tests := []string{
+		`{"kind":"moo","value":1}`,
+		`{"kind":"binary","value":{"op":"moo"}}`,
+		`{"kind":"number","value":"1/0"}`,
+		`{"kind":"integer","value":"1"}`,
+	}
+
+	for k, v := range tests {
+		_, err := UnmarshalJSON([]byte(v))
+		if err == nil {
+			t.Errorf("%v: expected error", k)
+		}
+	}
+}
+
+func TestJSONNestedFunction(t *testing.T) {
+	// the grammars can't produce a function inside a function
+	j := `{"kind":"operand","value":{"op":"program","nodes":[
+		{"kind":"func","value":{"name":"main","body":
+			{"kind":"func","value":{"name":"f","body":
+				{"kind":"operand","value":{"op":"eos"}}}}}}]}}`
+	n, err := UnmarshalJSON([]byte(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.Value.(NodeOperand).Nodes[0].Value.(FuncDecl); !ok {
+		t.Fatalf("invalid AST %v", n)
+	}
+
+	var w bytes.Buffer
+	err = DumpPseudoAsm(n, &w)
+	var d *diagnostics.Diagnostic
+	if !errors.As(err, &d) || d.Code != diagnostics.InvalidAST {
+		t.Fatalf("expected invalid AST, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package ast
+
+import "github.com/marcopeereboom/gck/diagnostics"
+
+// UnaryExpr is an operation on a single expression.
+// Op is one of Uminus, Not or Len.
+type UnaryExpr struct {
+	Op int
+	X  Node
+}
+
+// NewUnary returns an initialized UnaryExpr structure.
+func NewUnary(d *NodeDebugInformation, op int, x Node) Node {
+	return Node{
+		Debug: d,
+		Value: UnaryExpr{Op: op, X: x},
+	}
+}
+
+// BinaryExpr is an operation on two expressions.
+// Op is one of Add, Sub, Mul, Div, Lt, Gt, Le, Ge, Ne, Eq, And or Or.
+type BinaryExpr struct {
+	Op int
+	X  Node
+	Y  Node
+}
+
+// NewBinary returns an initialized BinaryExpr structure.
+func NewBinary(d *NodeDebugInformation, op int, x, y Node) Node {
+	return Node{
+		Debug: d,
+		Value: BinaryExpr{Op: op, X: x, Y: y},
+	}
+}
+
+// AssignStmt assigns Value to the Targets identifiers.
+// Multiple targets are only allowed when Value is a CallExpr that returns as
+// many results.
+type AssignStmt struct {
+	Targets []Node
+	Value   Node
+}
+
+// NewAssign returns an initialized AssignStmt structure.
+func NewAssign(d *NodeDebugInformation, targets []Node, value Node) Node {
+	return Node{
+		Debug: d,
+		Value: AssignStmt{Targets: targets, Value: value},
+	}
+}
+
+// DiscardStmt evaluates X and discards its results.
+type DiscardStmt struct {
+	X Node
+}
+
+// NewDiscard returns an initialized DiscardStmt structure.
+func NewDiscard(d *NodeDebugInformation, x Node) Node {
+	return Node{
+		Debug: d,
+		Value: DiscardStmt{X: x},
+	}
+}
+
+// IfNode executes Then when Cond is true and Else otherwise.
+// Else is an empty Node when there is no else branch.
+type IfNode struct {
+	Cond Node
+	Then Node
+	Else Node
+}
+
+// NewIf returns an initialized IfNode structure.
+func NewIf(d *NodeDebugInformation, cond, then, els Node) Node {
+	return Node{
+		Debug: d,
+		Value: IfNode{Cond: cond, Then: then, Else: els},
+	}
+}
+
+// WhileNode executes Body for as long as Cond is true.
+type WhileNode struct {
+	Cond Node
+	Body Node
+}
+
+// NewWhile returns an initialized WhileNode structure.
+func NewWhile(d *NodeDebugInformation, cond, body Node) Node {
+	return Node{
+		Debug: d,
+		Value: WhileNode{Cond: cond, Body: body},
+	}
+}
+
+// FuncDecl declares function Name.
+// Params and Results are identifiers.
+type FuncDecl struct {
+	Name    string
+	Params  []Node
+	Results []Node
+	Body    Node
+}
+
+// NewFunc returns an initialized FuncDecl structure.
+func NewFunc(d *NodeDebugInformation, name string, params, results []Node,
+	body Node) Node {
+
+	return Node{
+		Debug: d,
+		Value: FuncDecl{
+			Name:    name,
+			Params:  params,
+			Results: results,
+			Body:    body,
+		},
+	}
+}
+
+// CallExpr calls function Name with Args.
+type CallExpr struct {
+	Name string
+	Args []Node
+}
+
+// NewCall returns an initialized CallExpr structure.
+func NewCall(d *NodeDebugInformation, name string, args []Node) Node {
+	return Node{
+		Debug: d,
+		Value: CallExpr{Name: name, Args: args},
+	}
+}
+
+// GlobalDecl declares the Names identifiers global in a function.
+type GlobalDecl struct {
+	Names []Node
+}
+
+// NewGlobal returns an initialized GlobalDecl structure.
+func NewGlobal(d *NodeDebugInformation, names []Node) Node {
+	return Node{
+		Debug: d,
+		Value: GlobalDecl{Names: names},
+	}
+}
+
+// Nodes returns the nodes of List operand list.
+// This is a convenience function for parsers that collect nodes in lists.
+func Nodes(list Node) []Node {
+	return list.Value.(NodeOperand).Nodes
+}
+
+// isExpression returns true if n is a node that produces a value.
+func isExpression(n Node) bool {
+	switch v := n.Value.(type) {
+	case NodeIdentifier, NodeInteger, NodeString, NodeBool, UnaryExpr,
+		BinaryExpr, CallExpr:
+		return true
+	case NodeNumber:
+		return v.Value != nil
+	}
+	return false
+}
+
+// Validate verifies that AST n is well formed.
+// It returns an error for the first malformed node that is found.
+func Validate(n Node) error {
+	return validateTree(n, true)
+}
+
+// validateTree verifies n and its children.
+// top is true if n is the program or one of its statement lists, the only
+// places where functions may be declared.
+func validateTree(n Node, top bool) error {
+	if n.Value == nil {
+		return nil
+	}
+	if err := validate(n, top); err != nil {
+		return err
+	}
+	o, ok := n.Value.(NodeOperand)
+	top = top && ok && (o.Operand == Eos || o.Operand == Program)
+	for _, c := range children(n) {
+		if err := validateTree(c, top); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate verifies the shape of node n without looking at its children.
+// Functions are only accepted in the statement lists of n if top is true.
+func validate(n Node, top bool) error {
+	expr := func(what string, v Node) error {
+		if !isExpression(v) {
+			return Errorf(n, diagnostics.InvalidAST,
+				"%v: invalid expression %T", what, v.Value)
+		}
+		return nil
+	}
+	ids := func(what string, l []Node) error {
+		for _, v := range l {
+			if _, ok := v.Value.(NodeIdentifier); !ok {
+				return Errorf(n, diagnostics.InvalidAST,
+					"%v: expected identifier, got %T",
+					what, v.Value)
+			}
+		}
+		return nil
+	}
+	stmt := func(what string, v Node) error {
+		if v.Value == nil {
+			return Errorf(n, diagnostics.InvalidAST,
+				"%v: missing statement", what)
+		}
+		if isExpression(v) {
+			return Errorf(n, diagnostics.InvalidAST,
+				"%v: expression %T used as statement", what,
+				v.Value)
+		}
+		if f, ok := v.Value.(FuncDecl); ok {
+			return Errorf(n, diagnostics.InvalidAST,
+				"%v: function %v must be declared at top level",
+				what, f.Name)
+		}
+		return nil
+	}
+
+	switch v := n.Value.(type) {
+	case NodeIdentifier, NodeInteger, NodeString, NodeBool:
+	case NodeNumber:
+		if v.Value == nil {
+			return Errorf(n, diagnostics.InvalidAST,
+				"number without value")
+		}
+	case UnaryExpr:
+		switch v.Op {
+		case Uminus, Not, Len:
+		default:
+			return Errorf(n, diagnostics.InvalidAST,
+				"invalid unary operator %v", v.Op)
+		}
+		return expr(ops[v.Op], v.X)
+	case BinaryExpr:
+		switch v.Op {
+		case Add, Sub, Mul, Div, Lt, Gt, Le, Ge, Ne, Eq, And, Or:
+		default:
+			return Errorf(n, diagnostics.InvalidAST,
+				"invalid binary operator %v", v.Op)
+		}
+		if err := expr(ops[v.Op], v.X); err != nil {
+			return err
+		}
+		return expr(ops[v.Op], v.Y)
+	case AssignStmt:
+		if len(v.Targets) == 0 {
+			return Errorf(n, diagnostics.InvalidAST,
+				"assignment without target")
+		}
+		if err := ids("assignment", v.Targets); err != nil {
+			return err
+		}
+		if _, ok := v.Value.Value.(CallExpr); !ok &&
+			len(v.Targets) > 1 {
+			return Errorf(n, diagnostics.InvalidAST,
+				"multiple assignment requires a function call")
+		}
+		return expr("assignment", v.Value)
+	case DiscardStmt:
+		return expr("statement", v.X)
+	case IfNode:
+		if err := expr("if", v.Cond); err != nil {
+			return err
+		}
+		return stmt("if", v.Then)
+	case WhileNode:
+		if err := expr("while", v.Cond); err != nil {
+			return err
+		}
+		return stmt("while", v.Body)
+	case FuncDecl:
+		if v.Name == "" {
+			return Errorf(n, diagnostics.InvalidAST,
+				"function without name")
+		}
+		if err := ids("function "+v.Name, v.Params); err != nil {
+			return err
+		}
+		if err := ids("function "+v.Name, v.Results); err != nil {
+			return err
+		}
+		return stmt("function "+v.Name, v.Body)
+	case CallExpr:
+		if v.Name == "" {
+			return Errorf(n, diagnostics.InvalidAST,
+				"call without name")
+		}
+		for _, a := range v.Args {
+			if err := expr("call "+v.Name, a); err != nil {
+				return err
+			}
+		}
+	case GlobalDecl:
+		if len(v.Names) == 0 {
+			return Errorf(n, diagnostics.InvalidAST,
+				"global without names")
+		}
+		return ids("global", v.Names)
+	case NodeOperand:
+		switch v.Operand {
+		case Eos, Program:
+			for _, c := range v.Nodes {
+				if c.Value == nil {
+					continue
+				}
+				if _, ok := c.Value.(FuncDecl); ok && top {
+					continue
+				}
+				if err := stmt("statement list", c); err != nil {
+					return err
+				}
+			}
+		case List, NeedStart, Done:
+		default:
+			return Errorf(n, diagnostics.InvalidAST,
+				"invalid operand %v", v.Operand)
+		}
+	default:
+		return Errorf(n, diagnostics.InvalidAST,
+			"unknown node type %T", n.Value)
+	}
+
+	return nil
+}
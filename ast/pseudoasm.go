@@ -5,16 +5,116 @@ import (
 	"io"
 	"math/big"
 	"strings"
+
+	"github.com/marcopeereboom/gck/diagnostics"
 )
 
 type astResult struct {
-	code []string
-	ec   func(int, ...interface{}) error
-	lbl  int
+	code  []string
+	ec    func(int, ...interface{}) error
+	lbl   int
+	funcs map[string]*signature // function signatures by name
+
+	externs map[string]Extern // functions provided by the target
+}
+
+// signature describes the calling convention of a function.
+type signature struct {
+	params  []string // parameter names, pushed by caller in order
+	results []string // result names, pushed by callee in order
+}
+
+// identifiers returns the identifier names contained in list l.
+func identifiers(l []Node) ([]string, error) {
+	ids := make([]string, 0, len(l))
+	seen := make(map[string]bool)
+	for _, v := range l {
+		id, ok := v.Value.(NodeIdentifier)
+		if !ok {
+			return nil, Errorf(v, diagnostics.InvalidAST,
+				"expected identifier")
+		}
+		if seen[id.Value] {
+			return nil, Errorf(v, diagnostics.DuplicateIdent,
+				"duplicate identifier %v", id.Value)
+		}
+		seen[id.Value] = true
+		ids = append(ids, id.Value)
+	}
+
+	return ids, nil
+}
+
+// collectFunctions records the signatures of all functions in n.
+// This is done prior to emitting code so that calls to functions that are
+// declared later on can be validated.
+func (s *astResult) collectFunctions(n Node) error {
+	node, ok := n.Value.(FuncDecl)
+	if !ok {
+		for _, v := range children(n) {
+			err := s.collectFunctions(v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	name := node.Name
+	if _, found := s.funcs[name]; found {
+		return Errorf(n, diagnostics.DuplicateFunction,
+			"function %v redefined", name)
+	}
+	params, err := identifiers(node.Params)
+	if err != nil {
+		return err
+	}
+	results, err := identifiers(node.Results)
+	if err != nil {
+		return err
+	}
+	if name == "main" && (len(params) != 0 || len(results) != 0) {
+		return Errorf(n, diagnostics.InvalidMain,
+			"function main can not have parameters or results")
+	}
+	for _, v := range results {
+		for _, vv := range params {
+			if v == vv {
+				return Errorf(n, diagnostics.ShadowedParam,
+					"result %v shadows parameter", v)
+			}
+		}
+	}
+
+	s.funcs[name] = &signature{
+		params:  params,
+		results: results,
+	}
+
+	return nil
 }
 
-func (s *astResult) dumpCode(n Node, w io.Writer) error {
-	err := s.dumpCodeR(n)
+// generate validates n and calls s.ec for every pseudo opcode of n.
+func (s *astResult) generate(n Node, externs []Extern) error {
+	s.funcs = make(map[string]*signature)
+	s.externs = make(map[string]Extern)
+	for _, v := range externs {
+		s.externs[v.Name] = v
+	}
+	err := Validate(n)
+	if err != nil {
+		return err
+	}
+	err = s.collectFunctions(n)
+	if err != nil {
+		return err
+	}
+
+	return s.dumpCodeR(n)
+}
+
+func (s *astResult) dumpCode(n Node, w io.Writer, externs []Extern) error {
+	err := s.generate(n, externs)
 	if err != nil {
 		return err
 	}
@@ -47,6 +147,10 @@ func (s *astResult) emitPseudoAsm(t int, args ...interface{}) error {
 		s.addCode("\tpush\t%v\n", args[0].(int))
 	case NUMBER:
 		s.addCode("\tpush\t%v\n", args[0].(*big.Rat))
+	case STRING:
+		s.addCode("\tpush\t%q\n", args[0].(string))
+	case BOOLEAN:
+		s.addCode("\tpush\t%v\n", args[0].(bool))
 	case Assign:
 		s.addCode("\tpop\t%v\n", args[0].(string))
 	case Uminus:
@@ -59,6 +163,10 @@ func (s *astResult) emitPseudoAsm(t int, args ...interface{}) error {
 		s.addCode("\tmul\n")
 	case Div:
 		s.addCode("\tdiv\n")
+	case Len:
+		s.addCode("\tlen\n")
+	case Not:
+		s.addCode("\tnot\n")
 	case Le:
 		s.addCode("\tle\n")
 	case Ge:
@@ -81,10 +189,16 @@ func (s *astResult) emitPseudoAsm(t int, args ...interface{}) error {
 		s.addCode("\tjmp\tl%v\n", args[0])
 	case JSR:
 		s.addCode("\tjsr\t%v\n", args[0])
+	case CALL:
+		s.addCode("\tcall\t%v\n", args[0])
+	case DISCARD:
+		s.addCode("\tpop\tdiscard\n")
 	case RETURN:
 		s.addCode("\tret\n")
 	case DEBUG:
-		s.addCode(args[0].(string), args[1:]...)
+		// args are line number and source line
+		s.addCode("\n// line %v: %v\n", args[0],
+			strings.TrimSpace(args[1].(string)))
 	case FIXUP:
 		// nothing to do for pseudo asm
 	case NOP:
@@ -107,9 +221,145 @@ func (s *astResult) emitDebug(v Node) error {
 		lineNo = v.Debug.LineNo
 		line = v.Debug.Line
 	}
-	return s.ec(DEBUG, "\n// line %v: %v\n",
-		lineNo,
-		strings.Trim(line, " \r\t\n"))
+	return s.ec(DEBUG, lineNo, strings.TrimRight(line, "\r\n"))
+}
+
+// dumpBlock walks the body of a function or statement.
+// A body with a single statement is not a list so its line is emitted here.
+func (s *astResult) dumpBlock(n Node) error {
+	o, ok := n.Value.(NodeOperand)
+	if n.Debug != nil && (!ok || o.Operand != Eos) {
+		s.emitDebug(n)
+	}
+	return s.dumpCodeR(n)
+}
+
+// emitCall emits a call to the function described by call.
+// Arguments are pushed onto the stack in order and the callee pushes its
+// results in order.
+// Set want to the number of results the caller expects or to -1 to accept
+// any number of results.
+// It returns the number of results that the callee left on the stack.
+// Functions that are not defined in the source are looked up in the externs.
+func (s *astResult) emitCall(call Node, want int) (int, error) {
+	node := call.Value.(CallExpr)
+	name, args := node.Name, node.Args
+	sig, found := s.funcs[name]
+	if !found {
+		e, found := s.externs[name]
+		if !found {
+			return 0, Errorf(call, diagnostics.UndefinedFunction,
+				"undefined function %v", name)
+		}
+		return s.emitExtern(e, call, args, want)
+	}
+
+	if len(args) != len(sig.params) {
+		return 0, Errorf(call, diagnostics.ArgumentCount,
+			"function %v expects %v argument(s), got %v",
+			name, len(sig.params), len(args))
+	}
+	if want != -1 && want != len(sig.results) {
+		return 0, Errorf(call, diagnostics.ResultCount,
+			"function %v returns %v results, expected %v",
+			name, len(sig.results), want)
+	}
+
+	for _, v := range args {
+		err := s.dumpCodeR(v)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(sig.results), s.ec(JSR, name)
+}
+
+// emitExtern emits a call to function e that is provided by the target.
+// See emitCall for the meaning of want.
+func (s *astResult) emitExtern(e Extern, call Node, args []Node,
+	want int) (int, error) {
+
+	if len(args) != len(e.Params) {
+		return 0, Errorf(call, diagnostics.ArgumentCount,
+			"function %v expects %v argument(s), got %v",
+			e.Name, len(e.Params), len(args))
+	}
+	if want != -1 && want != len(e.Results) {
+		return 0, Errorf(call, diagnostics.ResultCount,
+			"function %v returns %v results, expected %v",
+			e.Name, len(e.Results), want)
+	}
+	for _, v := range args {
+		err := s.dumpCodeR(v)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(e.Results), s.ec(CALL, e.Name)
+}
+
+// shortCircuit emits a logical AND or OR.
+// The right hand side is only evaluated when the left hand side does not
+// determine the result.
+// For example a && b:
+//	push	a
+//	brf	l0
+//	push	b
+//	brf	l0
+//	push	true
+//	jmp	l1
+//	l0:
+//	push	false
+//	l1:
+// The result is always a normalized true or false value.
+func (s *astResult) shortCircuit(node BinaryExpr) error {
+	l0 := s.lbl // short circuit label
+	s.lbl++
+	l1 := s.lbl // past result label
+	s.lbl++
+
+	// AND short circuits on false, OR on true
+	branch, result := BRF, false
+	if node.Op == Or {
+		branch, result = BRT, true
+	}
+
+	for _, v := range []Node{node.X, node.Y} {
+		err := s.dumpCodeR(v)
+		if err != nil {
+			return err
+		}
+		err = s.ec(branch, l0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.ec(BOOLEAN, !result)
+	if err != nil {
+		return err
+	}
+	err = s.ec(JUMP, l1)
+	if err != nil {
+		return err
+	}
+	err = s.ec(LOCATION, l0)
+	if err != nil {
+		return err
+	}
+	err = s.ec(BOOLEAN, result)
+	if err != nil {
+		return err
+	}
+	err = s.ec(LOCATION, l1)
+	if err != nil {
+		return err
+	}
+
+	// fixup labels that didn't exist
+	return s.ec(FIXUP, l0, l1)
 }
 
 func (s *astResult) dumpCodeR(n Node) (err error) {
@@ -120,183 +370,264 @@ func (s *astResult) dumpCodeR(n Node) (err error) {
 		err = s.ec(INTEGER, node.Value)
 	case NodeNumber:
 		err = s.ec(NUMBER, node.Value)
-	case NodeOperand:
-		switch node.Operand {
-		case Assign:
-			err = s.dumpCodeR(node.Nodes[1])
+	case NodeString:
+		err = s.ec(STRING, node.Value)
+	case NodeBool:
+		err = s.ec(BOOLEAN, node.Value)
+
+	case AssignStmt:
+		if len(node.Targets) == 1 {
+			err = s.dumpCodeR(node.Value)
 			if err != nil {
 				return
 			}
-			err = s.ec(Assign, node.Nodes[0].Value.(NodeIdentifier).Value)
-
-		case Eos:
-			for _, v := range node.Nodes {
-				// the first few of those that are emitted
-				// should be ignored; would be nice to fix
-				s.emitDebug(v)
+			err = s.ec(Assign,
+				node.Targets[0].Value.(NodeIdentifier).Value)
+			return
+		}
 
-				// walk all nodes
-				err = s.dumpCodeR(v)
-				if err != nil {
-					return
-				}
+		// multiple assignment from function results
+		_, err = s.emitCall(node.Value, len(node.Targets))
+		if err != nil {
+			return
+		}
+		for i := len(node.Targets) - 1; i >= 0; i-- {
+			err = s.ec(Assign,
+				node.Targets[i].Value.(NodeIdentifier).Value)
+			if err != nil {
+				return
 			}
+		}
 
-		case Uminus:
-			err = s.dumpCodeR(node.Nodes[0])
+	case DiscardStmt:
+		results := 1
+		if _, ok := node.X.Value.(CallExpr); ok {
+			results, err = s.emitCall(node.X, -1)
+		} else {
+			err = s.dumpCodeR(node.X)
+		}
+		if err != nil {
+			return
+		}
+		for i := 0; i < results; i++ {
+			err = s.ec(DISCARD)
 			if err != nil {
 				return
 			}
-			err = s.ec(Uminus)
-
-		case If:
-			if len(node.Nodes) == 2 {
-				l0 := s.lbl
-				s.lbl++
-
-				// bool expression
-				err = s.dumpCodeR(node.Nodes[0])
-				if err != nil {
-					return
-				}
-				err = s.ec(BRF, l0)
-				if err != nil {
-					return
-				}
-
-				// body
-				err = s.dumpCodeR(node.Nodes[1])
-				if err != nil {
-					return
-				}
-
-				// label past body
-				err = s.ec(LOCATION, l0)
-				if err != nil {
-					return
-				}
-
-				// fixup labels that didn't exist
-				err = s.ec(FIXUP, l0)
-				if err != nil {
-					return
-				}
-
-			} else {
-				l0 := s.lbl
-				s.lbl++
-				l1 := s.lbl
-				s.lbl++
-
-				// bool expression
-				err = s.dumpCodeR(node.Nodes[0])
-				if err != nil {
-					return
-				}
-				err = s.ec(BRF, l0)
-				if err != nil {
-					return
-				}
-
-				// body
-				err = s.dumpCodeR(node.Nodes[1])
-				if err != nil {
-					return
-				}
-				// jmp past else body
-				err = s.ec(JUMP, l1)
-				if err != nil {
-					return
-				}
+		}
 
-				// label past body
-				err = s.ec(LOCATION, l0)
-				if err != nil {
-					return
-				}
+	case UnaryExpr:
+		err = s.dumpCodeR(node.X)
+		if err != nil {
+			return
+		}
+		err = s.ec(node.Op)
 
-				err = s.dumpCodeR(node.Nodes[2])
-				if err != nil {
-					return
-				}
+	case BinaryExpr:
+		if node.Op == And || node.Op == Or {
+			err = s.shortCircuit(node)
+			return
+		}
+		err = s.dumpCodeR(node.X)
+		if err != nil {
+			return
+		}
+		err = s.dumpCodeR(node.Y)
+		if err != nil {
+			return
+		}
+		err = s.ec(node.Op)
 
-				// label past else body
-				err = s.ec(LOCATION, l1)
-				if err != nil {
-					return
-				}
+	case IfNode:
+		if node.Else.Value == nil {
+			l0 := s.lbl
+			s.lbl++
 
-				// fixup labels that didn't exist
-				err = s.ec(FIXUP, l0, l1)
-				if err != nil {
-					return
-				}
+			// bool expression
+			err = s.dumpCodeR(node.Cond)
+			if err != nil {
+				return
+			}
+			err = s.ec(BRF, l0)
+			if err != nil {
+				return
+			}
 
+			// body
+			err = s.dumpBlock(node.Then)
+			if err != nil {
+				return
 			}
-		case While:
-			l0 := s.lbl // loop label
-			s.lbl++
-			l1 := s.lbl // past body label
-			s.lbl++
 
-			// boolean check
+			// label past body
 			err = s.ec(LOCATION, l0)
 			if err != nil {
 				return
 			}
-			err = s.dumpCodeR(node.Nodes[0])
+
+			// fixup labels that didn't exist
+			err = s.ec(FIXUP, l0)
+			if err != nil {
+				return
+			}
+
+		} else {
+			l0 := s.lbl
+			s.lbl++
+			l1 := s.lbl
+			s.lbl++
+
+			// bool expression
+			err = s.dumpCodeR(node.Cond)
 			if err != nil {
 				return
 			}
-			err = s.ec(BRF, l1)
+			err = s.ec(BRF, l0)
 			if err != nil {
 				return
 			}
 
 			// body
-			err = s.dumpCodeR(node.Nodes[1])
+			err = s.dumpBlock(node.Then)
 			if err != nil {
 				return
 			}
-			err = s.ec(JUMP, l0)
+			// jmp past else body
+			err = s.ec(JUMP, l1)
 			if err != nil {
 				return
 			}
-			err = s.ec(LOCATION, l1)
+
+			// label past body
+			err = s.ec(LOCATION, l0)
 			if err != nil {
 				return
 			}
 
-			// fixup labels that didn't exist
-			err = s.ec(FIXUP, l1)
+			err = s.dumpBlock(node.Else)
 			if err != nil {
 				return
 			}
 
-		case Function:
-			// Nodes[0] == function name
-			// Nodes[1] == function body
-			err = s.ec(LOCATION,
-				node.Nodes[0].Value.(NodeIdentifier).Value)
+			// label past else body
+			err = s.ec(LOCATION, l1)
 			if err != nil {
 				return
 			}
-			err = s.dumpCodeR(node.Nodes[1])
+
+			// fixup labels that didn't exist
+			err = s.ec(FIXUP, l0, l1)
 			if err != nil {
 				return
 			}
-			err = s.ec(RETURN)
+
+		}
+
+	case WhileNode:
+		l0 := s.lbl // loop label
+		s.lbl++
+		l1 := s.lbl // past body label
+		s.lbl++
+
+		// boolean check
+		err = s.ec(LOCATION, l0)
+		if err != nil {
+			return
+		}
+		err = s.dumpCodeR(node.Cond)
+		if err != nil {
+			return
+		}
+		err = s.ec(BRF, l1)
+		if err != nil {
+			return
+		}
+
+		// body
+		err = s.dumpBlock(node.Body)
+		if err != nil {
+			return
+		}
+		err = s.ec(JUMP, l0)
+		if err != nil {
+			return
+		}
+		err = s.ec(LOCATION, l1)
+		if err != nil {
+			return
+		}
+
+		// fixup labels that didn't exist
+		err = s.ec(FIXUP, l1)
+		if err != nil {
+			return
+		}
+
+	case FuncDecl:
+		sig, found := s.funcs[node.Name]
+		if !found {
+			// only functions at top level are collected
+			err = Errorf(n, diagnostics.InvalidAST,
+				"function %v is not declared at top level",
+				node.Name)
+			return
+		}
+		err = s.ec(LOCATION, node.Name)
+		if err != nil {
+			return
+		}
+
+		// arguments were pushed in order so pop them in reverse
+		for i := len(sig.params) - 1; i >= 0; i-- {
+			err = s.ec(Assign, sig.params[i])
 			if err != nil {
 				return
 			}
+		}
+
+		err = s.dumpBlock(node.Body)
+		if err != nil {
+			return
+		}
 
-		case FunctionCall:
-			// Nodes[0] == function name
-			err = s.ec(JSR,
-				node.Nodes[0].Value.(NodeIdentifier).Value)
+		// push results in order for the caller
+		for _, v := range sig.results {
+			err = s.ec(IDENTIFIER, v)
 			if err != nil {
 				return
 			}
+		}
+		err = s.ec(RETURN)
+		if err != nil {
+			return
+		}
+
+	case CallExpr:
+		// a call that is part of an expression must return exactly
+		// one value
+		_, err = s.emitCall(n, 1)
+		if err != nil {
+			return
+		}
+
+	case GlobalDecl:
+		// scope declaration, nothing to emit
+
+	case NodeOperand:
+		switch node.Operand {
+		case Eos:
+			for _, v := range node.Nodes {
+				// the first few of those that are emitted
+				// should be ignored; would be nice to fix
+				s.emitDebug(v)
+
+				// walk all nodes
+				err = s.dumpCodeR(v)
+				if err != nil {
+					return
+				}
+			}
+
 		case NeedStart:
 			// emit main label, language does not do that
 			err = s.ec(NEEDSTART)
@@ -325,44 +656,13 @@ func (s *astResult) dumpCodeR(n Node) (err error) {
 				}
 			}
 		default:
-			err = s.dumpCodeR(node.Nodes[0])
-			if err != nil {
-				return
-			}
-			err = s.dumpCodeR(node.Nodes[1])
-			if err != nil {
-				return
-			}
-			switch node.Operand {
-			case Add:
-				err = s.ec(Add)
-			case Sub:
-				err = s.ec(Sub)
-			case Mul:
-				err = s.ec(Mul)
-			case Div:
-				err = s.ec(Div)
-			case Lt:
-				err = s.ec(Lt)
-			case Gt:
-				err = s.ec(Gt)
-			case Le:
-				err = s.ec(Le)
-			case Ge:
-				err = s.ec(Ge)
-			case Eq:
-				err = s.ec(Eq)
-			case Ne:
-				err = s.ec(Ne)
-			default:
-				err = fmt.Errorf("unknown operand %v%v",
-					node.Operand, ExtraDebug(n))
-				return
-			}
+			err = Errorf(n, diagnostics.InvalidAST,
+				"unknown operand %v", node.Operand)
+			return
 		}
 	default:
-		err = fmt.Errorf("unknown node type %T%v",
-			node, ExtraDebug(n))
+		err = Errorf(n, diagnostics.InvalidAST, "unknown node type %T",
+			node)
 		return
 	}
 
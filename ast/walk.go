@@ -0,0 +1,174 @@
+package ast
+
+// Visitor is called for every node that is encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children of
+// node n with the visitor w, followed by a call of w.Visit(Node{}).
+type Visitor interface {
+	Visit(n Node) (w Visitor)
+}
+
+// Walk traverses AST n in depth-first order.
+// It starts by calling v.Visit(n); n must not be empty.
+// If the visitor w returned by v.Visit(n) is not nil, Walk is invoked
+// recursively with visitor w for each of the children of n, followed by a
+// call of w.Visit(Node{}).
+func Walk(v Visitor, n Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+
+	for _, c := range children(n) {
+		Walk(v, c)
+	}
+
+	v.Visit(Node{})
+}
+
+// inspector adapts a function to the Visitor interface.
+type inspector func(Node) bool
+
+func (f inspector) Visit(n Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses AST n in depth-first order.
+// It starts by calling f(n); n must not be empty.
+// If f returns true, Inspect invokes f recursively for each of the children of
+// n, followed by a call of f(Node{}).
+func Inspect(n Node, f func(Node) bool) {
+	Walk(inspector(f), n)
+}
+
+// Rewrite returns a copy of AST n in which every node has been replaced by the
+// result of f.
+// Nodes are rewritten bottom-up, f is called with a node after its children
+// have been rewritten.
+// Operands are copied prior to calling f, so f can modify the node it was
+// called with without affecting n; leaves are passed as is.
+func Rewrite(n Node, f func(Node) Node) Node {
+	c := children(n)
+	if c == nil {
+		return f(n)
+	}
+
+	r := make([]Node, 0, len(c))
+	for _, v := range c {
+		r = append(r, Rewrite(v, f))
+	}
+	return f(withChildren(n, r))
+}
+
+// children returns the child nodes of n in evaluation order.
+// Leaves return nil, nodes without children return an empty slice and an
+// absent else branch is not a child.
+func children(n Node) []Node {
+	switch nn := n.Value.(type) {
+	case Node:
+		return []Node{nn}
+	case NodeOperand:
+		return nonNil(nn.Nodes)
+	case UnaryExpr:
+		return []Node{nn.X}
+	case BinaryExpr:
+		return []Node{nn.X, nn.Y}
+	case AssignStmt:
+		return append(append([]Node{}, nn.Targets...), nn.Value)
+	case DiscardStmt:
+		return []Node{nn.X}
+	case IfNode:
+		if nn.Else.Value == nil {
+			return []Node{nn.Cond, nn.Then}
+		}
+		return []Node{nn.Cond, nn.Then, nn.Else}
+	case WhileNode:
+		return []Node{nn.Cond, nn.Body}
+	case FuncDecl:
+		c := make([]Node, 0, len(nn.Params)+len(nn.Results)+1)
+		c = append(c, nn.Params...)
+		c = append(c, nn.Results...)
+		return append(c, nn.Body)
+	case CallExpr:
+		return nonNil(nn.Args)
+	case GlobalDecl:
+		return nonNil(nn.Names)
+	}
+
+	return nil
+}
+
+// nonNil returns l or an empty slice if l is nil.
+func nonNil(l []Node) []Node {
+	if l == nil {
+		return []Node{}
+	}
+	return l
+}
+
+// withChildren returns a copy of n in which the children have been replaced
+// by c.
+// The number and order of c must match what children returned for n; the
+// returned node does not share any slices with n.
+func withChildren(n Node, c []Node) Node {
+	nodes := func(l []Node) []Node {
+		return append([]Node{}, l...)
+	}
+
+	r := Node{Debug: n.Debug}
+	switch nn := n.Value.(type) {
+	case Node:
+		r.Value = c[0]
+	case NodeOperand:
+		if nn.Nodes != nil {
+			nn.Nodes = nodes(c)
+		}
+		r.Value = nn
+	case UnaryExpr:
+		nn.X = c[0]
+		r.Value = nn
+	case BinaryExpr:
+		nn.X, nn.Y = c[0], c[1]
+		r.Value = nn
+	case AssignStmt:
+		nn.Targets = nodes(c[:len(c)-1])
+		nn.Value = c[len(c)-1]
+		r.Value = nn
+	case DiscardStmt:
+		nn.X = c[0]
+		r.Value = nn
+	case IfNode:
+		nn.Cond, nn.Then = c[0], c[1]
+		if len(c) > 2 {
+			nn.Else = c[2]
+		}
+		r.Value = nn
+	case WhileNode:
+		nn.Cond, nn.Body = c[0], c[1]
+		r.Value = nn
+	case FuncDecl:
+		p, rs := len(nn.Params), len(nn.Results)
+		if nn.Params != nil {
+			nn.Params = nodes(c[:p])
+		}
+		if nn.Results != nil {
+			nn.Results = nodes(c[p : p+rs])
+		}
+		nn.Body = c[p+rs]
+		r.Value = nn
+	case CallExpr:
+		if nn.Args != nil {
+			nn.Args = nodes(c)
+		}
+		r.Value = nn
+	case GlobalDecl:
+		if nn.Names != nil {
+			nn.Names = nodes(c)
+		}
+		r.Value = nn
+	default:
+		r.Value = n.Value
+	}
+	return r
+}
@@ -7,4 +7,5 @@ import "github.com/marcopeereboom/gck/ast"
 type Backend interface {
 	EmitCode(ast.Node) ([]byte, error) // return target architecture binary
 	Error() error                      // returns errors that are not detected by EmitCode
+	Externs() []ast.Extern             // functions provided by the architecture
 }
@@ -0,0 +1,113 @@
+package tvm
+
+import (
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+// scope contains the local variables of a function.
+// Parameters and results are always local.
+// Variables that are assigned inside a function are local unless they are
+// explicitly declared global.
+// All other variables resolve to the global .VAR section.
+type scope struct {
+	slots   map[string]uint64 // local slot by name
+	globals map[string]bool   // variables declared global
+}
+
+// newScope returns an empty scope.
+func newScope() *scope {
+	return &scope{
+		slots:   make(map[string]uint64),
+		globals: make(map[string]bool),
+	}
+}
+
+// add allocates a slot for name if it doesn't have one yet.
+func (s *scope) add(name string) {
+	if _, found := s.slots[name]; found {
+		return
+	}
+	s.slots[name] = uint64(len(s.slots))
+}
+
+// resolveScopes creates a scope for every function in n.
+func (t *ToyVirtualMachine) resolveScopes(n ast.Node) error {
+	var err error
+	ast.Inspect(n, func(c ast.Node) bool {
+		node, ok := c.Value.(ast.FuncDecl)
+		if !ok || err != nil {
+			return err == nil
+		}
+		err = t.resolveScope(node)
+		return false
+	})
+	return err
+}
+
+// resolveScope creates the scope of function node.
+func (t *ToyVirtualMachine) resolveScope(node ast.FuncDecl) error {
+	s := newScope()
+
+	// globals must be known before assignments are looked at
+	s.findGlobals(node.Body)
+
+	for _, list := range [][]ast.Node{node.Params, node.Results} {
+		for _, v := range list {
+			id := v.Value.(ast.NodeIdentifier).Value
+			if s.globals[id] {
+				return ast.Errorf(v, diagnostics.InvalidGlobal,
+					"%v can not be declared global in "+
+						"function %v", id, node.Name)
+			}
+			s.add(id)
+		}
+	}
+
+	s.findLocals(node.Body)
+	t.scopes[node.Name] = s
+
+	return nil
+}
+
+// findGlobals records all variables that are declared global in n.
+func (s *scope) findGlobals(n ast.Node) {
+	ast.Inspect(n, func(c ast.Node) bool {
+		node, ok := c.Value.(ast.GlobalDecl)
+		if !ok {
+			return true
+		}
+		for _, v := range node.Names {
+			s.globals[v.Value.(ast.NodeIdentifier).Value] = true
+		}
+		return false
+	})
+}
+
+// findLocals allocates slots for all variables that are assigned in n and
+// that are not declared global.
+func (s *scope) findLocals(n ast.Node) {
+	ast.Inspect(n, func(c ast.Node) bool {
+		node, ok := c.Value.(ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for _, v := range node.Targets {
+			id := v.Value.(ast.NodeIdentifier).Value
+			if !s.globals[id] {
+				s.add(id)
+			}
+		}
+		return true
+	})
+}
+
+// local returns the slot of name if it is a local variable in the function
+// that is currently being emitted.
+func (t *ToyVirtualMachine) local(name string) (uint64, bool) {
+	if t.scope == nil {
+		return 0, false
+	}
+	slot, found := t.scope.slots[name]
+	return slot, found
+}
@@ -3,13 +3,15 @@ package tvm
 
 import (
 	"bytes"
-	"fmt"
 	"math/big"
+	"reflect"
 	"strconv"
 
 	"github.com/marcopeereboom/gck/ast"
 	"github.com/marcopeereboom/gck/backend/arch"
+	"github.com/marcopeereboom/gck/diagnostics"
 	"github.com/marcopeereboom/gck/tvm/section"
+	"github.com/marcopeereboom/gck/tvm/stdlib"
 	"github.com/marcopeereboom/gck/tvm/vm"
 )
 
@@ -21,20 +23,37 @@ type ToyVirtualMachine struct {
 	varsA   []*section.Variable
 	vars    map[string]*section.Variable // lookup by name
 	lbls    map[int]uint64               // labels by id
-	fixup   map[int]uint64               // labels that need fixing up
+	fixup   map[int][]uint64             // labels that need fixing up
 	code    []uint64
+	scopes  map[string]*scope // function scopes by name
+	scope   *scope            // scope of function being emitted
+	funcs   *stdlib.Registry  // functions provided by the vm
+	oss     []*section.Os
+	ossL    map[string]*section.Os // lookup by name
+	lines   []section.Line         // source lines by code location
 }
 
 // ensure interface is met
 var _ arch.Backend = &ToyVirtualMachine{}
 
 // New creates a new ToyVirtualMachine context.
+// Programs can call the builtin stdlib functions.
 func New() (*ToyVirtualMachine, error) {
+	return NewWithRegistry(stdlib.NewRegistry())
+}
+
+// NewWithRegistry creates a new ToyVirtualMachine context for programs that
+// can call the functions in registry r.
+// The resulting image must be run by a vm that uses the same registry.
+func NewWithRegistry(r *stdlib.Registry) (*ToyVirtualMachine, error) {
 	vm := ToyVirtualMachine{
+		funcs:   r,
+		ossL:    make(map[string]*section.Os),
 		vars:    make(map[string]*section.Variable),
 		constsL: make(map[string]*section.Const),
 		lbls:    make(map[int]uint64),
-		fixup:   make(map[int]uint64),
+		fixup:   make(map[int][]uint64),
+		scopes:  make(map[string]*scope),
 		id:      1000,
 		code:    make([]uint64, 0, 1000),
 	}
@@ -55,21 +74,52 @@ func (t *ToyVirtualMachine) Error() error {
 	// check for main
 	m, found := t.constsL["main"]
 	if !found || (found && m.Value == "-1") {
-		return fmt.Errorf("function main not found")
+		return diagnostics.Errorf(diagnostics.Pos{},
+			diagnostics.MissingMain, "function main not found")
 	}
 
 	// all requirements met
 	return nil
 }
 
+// Externs implements the arch.Backend interface.
+// It returns the signatures of the functions in the registry.
+func (t *ToyVirtualMachine) Externs() []ast.Extern {
+	var externs []ast.Extern
+	for _, f := range t.funcs.Functions() {
+		externs = append(externs, ast.Extern{
+			Name:    f.Name,
+			Params:  typeNames(f.Args),
+			Results: typeNames(f.Results),
+		})
+	}
+	return externs
+}
+
+// typeNames converts stdlib types to ast type names.
+func typeNames(types []reflect.Type) []string {
+	names := make([]string, 0, len(types))
+	for _, v := range types {
+		// the registry only contains valid types
+		name, _ := section.OsTypeName(v)
+		names = append(names, name)
+	}
+	return names
+}
+
 // EmitCode implements the arch.Backend interface.
 // It converts ast.Node n into code, variables, contants etc.
 // The result is a Toy Virtual Machine image that can be executed.
 func (t *ToyVirtualMachine) EmitCode(n ast.Node) ([]byte, error) {
 	var image []byte
 
+	err := t.resolveScopes(n)
+	if err != nil {
+		return nil, err
+	}
+
 	w := bytes.NewBuffer(image)
-	err := ast.EmitCode(n, w, t.emitCode)
+	err = ast.EmitCode(n, w, t.emitCode, t.Externs()...)
 	if err != nil {
 		return nil, err
 	}
@@ -82,26 +132,55 @@ func (t *ToyVirtualMachine) EmitCode(n ast.Node) ([]byte, error) {
 		return nil, err
 	}
 
-	vs, err := section.NewVariableSection(t.varsA)
-	if err != nil {
-		return nil, err
-	}
-
 	// generate image
 	i := section.NewImage()
 	err = i.AddSection(cs, true)
 	if err != nil {
 		return nil, err
 	}
-	err = i.AddSection(vs, true)
-	if err != nil {
-		return nil, err
+
+	// variables are optional since functions may only use locals
+	if len(t.varsA) != 0 {
+		vs, err := section.NewVariableSection(t.varsA)
+		if err != nil {
+			return nil, err
+		}
+		err = i.AddSection(vs, true)
+		if err != nil {
+			return nil, err
+		}
 	}
 	err = i.AddSection(cos, true)
 	if err != nil {
 		return nil, err
 	}
 
+	// debug information is only emitted when the ast has line numbers
+	if len(t.lines) != 0 {
+		ds, err := section.NewDebugSection(&section.Debug{
+			Lines: t.lines,
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = i.AddSection(ds, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// os calls are only emitted when used
+	if len(t.oss) != 0 {
+		oss, err := section.NewOsSection(t.oss)
+		if err != nil {
+			return nil, err
+		}
+		err = i.AddSection(oss, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return i.GetImage(), nil
 }
 
@@ -113,6 +192,18 @@ func (t *ToyVirtualMachine) addCode(code []uint64) {
 	}
 }
 
+// addLine records that the code that is emitted next belongs to source line
+// lineNo with text.
+func (t *ToyVirtualMachine) addLine(lineNo uint64, text string) {
+	line := section.Line{PC: uint64(len(t.code)), LineNo: lineNo, Text: text}
+	if n := len(t.lines); n != 0 && t.lines[n-1].PC == line.PC {
+		// no code was emitted for the previous line
+		t.lines[n-1] = line
+		return
+	}
+	t.lines = append(t.lines, line)
+}
+
 // getVar looks up a variable by name and return a new Variable structure if
 // the variable name does not exist.
 // If the variable name does exist it returns the existing structure instead.
@@ -150,8 +241,12 @@ func (t *ToyVirtualMachine) getConst(value interface{}) (*section.Const, error)
 		v = val.String()
 	case int:
 		v = strconv.Itoa(val)
+	case string:
+		// quote to prevent collisions with numbers and functions
+		v = strconv.Quote(val)
 	default:
-		return nil, fmt.Errorf("invalid type for .CONST %T", value)
+		return nil, diagnostics.Errorf(diagnostics.Pos{},
+			diagnostics.Internal, "invalid type for .CONST %T", value)
 	}
 
 	c, found := t.constsL[v]
@@ -190,10 +285,49 @@ func (t *ToyVirtualMachine) getFunc(value string) (*section.Const, error) {
 	return c, nil
 }
 
+// getOs looks up an os call by name and returns a new Os structure if the
+// name does not exist.
+// If the name does exist it returns the existing structure instead.
+func (t *ToyVirtualMachine) getOs(name string) (*section.Os, error) {
+	o, found := t.ossL[name]
+	if found {
+		return o, nil
+	}
+
+	f, found := t.funcs.Lookup(name)
+	if !found {
+		return nil, diagnostics.Errorf(diagnostics.Pos{},
+			diagnostics.UndefinedFunction, "os call not found: %v", name)
+	}
+	id := t.newId()
+	oc := section.OsCall{
+		Id:   id,
+		Name: name,
+	}
+	for _, v := range f.Args {
+		oc.Variables = append(oc.Variables, section.OsInOut{Type: v})
+	}
+	for _, v := range f.Results {
+		oc.Results = append(oc.Results, section.OsInOut{Type: v})
+	}
+	o, err := section.NewOs(id, name, oc)
+	if err != nil {
+		return nil, err
+	}
+	t.oss = append(t.oss, o)
+	t.ossL[name] = o
+
+	return o, nil
+}
+
 // emitCode convert ast.Node into code, variables and constants.
 func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 	switch ty {
 	case ast.IDENTIFIER:
+		if slot, found := t.local(args[0].(string)); found {
+			t.addCode([]uint64{vm.OP_PUSHL, slot})
+			break
+		}
 		va, err := t.getVar(args[0].(string))
 		if err != nil {
 			return err
@@ -214,7 +348,18 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		}
 		t.addCode([]uint64{vm.OP_PUSH, c.Id})
 
+	case ast.STRING:
+		c, err := t.getConst(args[0].(string))
+		if err != nil {
+			return err
+		}
+		t.addCode([]uint64{vm.OP_PUSH, c.Id})
+
 	case ast.Assign:
+		if slot, found := t.local(args[0].(string)); found {
+			t.addCode([]uint64{vm.OP_POPL, slot})
+			break
+		}
 		va, err := t.getVar(args[0].(string))
 		if err != nil {
 			return err
@@ -236,6 +381,20 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 	case ast.Div:
 		t.addCode([]uint64{vm.OP_DIV})
 
+	case ast.Len:
+		t.addCode([]uint64{vm.OP_LEN})
+
+	case ast.Not:
+		t.addCode([]uint64{vm.OP_NOT})
+
+	case ast.BOOLEAN:
+		// booleans are reserved symbols
+		b := uint64(section.SymReservedFalse)
+		if args[0].(bool) {
+			b = section.SymReservedTrue
+		}
+		t.addCode([]uint64{vm.OP_PUSH, b})
+
 	case ast.Lt:
 		t.addCode([]uint64{vm.OP_LT})
 
@@ -260,7 +419,7 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		if !ok {
 			// store fixup location as [label index] memory location
 			jl = 0xffffffffffffffff
-			t.fixup[i] = uint64(len(t.code)) + 1
+			t.fixup[i] = append(t.fixup[i], uint64(len(t.code))+1)
 		}
 		t.addCode([]uint64{vm.OP_BRT, jl})
 
@@ -270,7 +429,7 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		if !ok {
 			// store fixup location as [label index] memory location
 			jl = 0xffffffffffffffff
-			t.fixup[i] = uint64(len(t.code)) + 1
+			t.fixup[i] = append(t.fixup[i], uint64(len(t.code))+1)
 		}
 		t.addCode([]uint64{vm.OP_BRF, jl})
 
@@ -280,7 +439,7 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		if !ok {
 			// store fixup location as [label index] memory location
 			jl = 0xffffffffffffffff
-			t.fixup[i] = uint64(len(t.code)) + 1
+			t.fixup[i] = append(t.fixup[i], uint64(len(t.code))+1)
 		}
 		t.addCode([]uint64{vm.OP_JMP, jl})
 
@@ -291,6 +450,13 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		}
 		t.addCode([]uint64{vm.OP_JSR, f.Id})
 
+	case ast.CALL:
+		o, err := t.getOs(args[0].(string))
+		if err != nil {
+			return err
+		}
+		t.addCode([]uint64{vm.OP_CALL, o.Id})
+
 	case ast.LOCATION:
 		// int -> label
 		// string -> function
@@ -298,7 +464,9 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		case int:
 			_, found := t.lbls[a]
 			if found {
-				return fmt.Errorf("label already exists %v", a)
+				return diagnostics.Errorf(diagnostics.Pos{},
+					diagnostics.DuplicateLabel,
+					"label already exists %v", a)
 			}
 			t.lbls[a] = uint64(len(t.code))
 
@@ -318,19 +486,32 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 
 			// add to contant list now that we know the value
 			t.consts = append(t.consts, c)
+
+			// allocate locals of the function
+			t.scope = t.scopes[a]
+			if t.scope != nil && len(t.scope.slots) != 0 {
+				t.addCode([]uint64{vm.OP_ENTER,
+					uint64(len(t.scope.slots))})
+			}
 		}
 
 	case ast.FIXUP:
 		for _, v := range args {
 			// v = label index
-			// t.fixup[v] = memory location that needs to be fixed
+			// t.fixup[v] = memory locations that need to be fixed
 			// t.lbls[v] = value for fixup
-			t.code[t.fixup[v.(int)]] = t.lbls[v.(int)]
+			for _, l := range t.fixup[v.(int)] {
+				t.code[l] = t.lbls[v.(int)]
+			}
+			delete(t.fixup, v.(int))
 		}
 
 	case ast.RETURN:
 		t.addCode([]uint64{vm.OP_RET})
 
+	case ast.DISCARD:
+		t.addCode([]uint64{vm.OP_POP, section.SymReservedDiscard})
+
 	case ast.NOP:
 		t.addCode([]uint64{vm.OP_NOP})
 
@@ -365,10 +546,20 @@ func (t *ToyVirtualMachine) emitCode(ty int, args ...interface{}) error {
 		}
 
 	case ast.DEBUG:
-		// ignore
+		// args are line number and source line
+		if len(args) < 2 {
+			break
+		}
+		lineNo, ok := args[0].(int)
+		if !ok || lineNo <= 0 {
+			break
+		}
+		text, _ := args[1].(string)
+		t.addLine(uint64(lineNo), text)
 
 	default:
-		return fmt.Errorf("unsuported pseudo opcode %v", ty)
+		return diagnostics.Errorf(diagnostics.Pos{},
+			diagnostics.Internal, "unsuported pseudo opcode %v", ty)
 	}
 
 	return nil
@@ -0,0 +1,118 @@
+package tvm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marcopeereboom/gck/frontend/myrmidon"
+	"github.com/marcopeereboom/gck/tvm/vm"
+)
+
+// run compiles Myrmidon program src, runs it and returns the values of its
+// global variables by name.
+func run(t *testing.T, src string) map[string]string {
+	m, err := myrmidon.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := m.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := b.EmitCode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := vm.New(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Run()
+	if err != vm.ErrExit {
+		t.Fatal(err)
+	}
+
+	// symbols are printed as "name (value)"
+	g := make(map[string]string)
+	for _, s := range strings.Split(v.GetSymbols(false), "\n") {
+		i := strings.LastIndex(s, " (")
+		if i == -1 || !strings.HasSuffix(s, ")") {
+			continue
+		}
+		g[s[:i]] = s[i+2 : len(s)-1]
+	}
+	return g
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]string
+	}{
+		{
+			name: "recursion",
+			src: "func fact (n) (r) {\n" +
+				"\tr = 1;\n" +
+				"\tif n > 1 {\n" +
+				"\t\tr = n * fact(n - 1);\n" +
+				"\t}\n" +
+				"}\n" +
+				"func main () () {\n" +
+				"\tglobal f;\n" +
+				"\tf = fact(10);\n" +
+				"}\n",
+			want: map[string]string{"f": "3628800"},
+		},
+		{
+			// the locals of every call are retained across calls
+			name: "locals",
+			src: "func fib (n) (f) {\n" +
+				"\tf = n;\n" +
+				"\tif n > 1 {\n" +
+				"\t\ta = fib(n - 1);\n" +
+				"\t\tb = fib(n - 2);\n" +
+				"\t\tf = a + b;\n" +
+				"\t}\n" +
+				"}\n" +
+				"func main () () {\n" +
+				"\tglobal x, y;\n" +
+				"\ta = 1;\n" +
+				"\tx = fib(15);\n" +
+				"\ty = a;\n" +
+				"}\n",
+			want: map[string]string{"x": "610", "y": "1"},
+		},
+		{
+			name: "multiple results",
+			src: "func sumdiff (a, b) (s, d) {\n" +
+				"\ts = a + b;\n" +
+				"\td = a - b;\n" +
+				"}\n" +
+				"func main () () {\n" +
+				"\tglobal s, d;\n" +
+				"\ts, d = sumdiff(17, 5);\n" +
+				"}\n",
+			want: map[string]string{"s": "22", "d": "12"},
+		},
+	}
+	for _, v := range tests {
+		got := run(t, v.src)
+		for name, want := range v.want {
+			if got[name] != want {
+				t.Fatalf("%v: %v = %v, want %v", v.name, name,
+					got[name], want)
+			}
+		}
+	}
+}
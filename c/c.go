@@ -2,17 +2,22 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/marcopeereboom/gck/ast"
 	"github.com/marcopeereboom/gck/backend"
+	"github.com/marcopeereboom/gck/diagnostics"
 	"github.com/marcopeereboom/gck/frontend"
 	"github.com/marcopeereboom/gck/optimizer"
+	"github.com/marcopeereboom/gck/semantic"
 )
 
 var (
@@ -21,12 +26,49 @@ var (
 	in       string
 	out      string
 	pAST     bool
+	pASTJSON bool
 	pASM     bool
-	optimize bool
+	dot      string
+	format   bool
+	fromAST  string
+	optLevel level
+	passes   string
+	stats    bool
+	diagJSON bool
 )
 
+// level is an optimization level flag.
+// It can be used as a boolean, -O means -O=1.
+type level int
+
+func (l *level) String() string {
+	return strconv.Itoa(int(*l))
+}
+
+func (l *level) Set(s string) error {
+	switch s {
+	case "true":
+		*l = 1
+		return nil
+	case "false":
+		*l = 0
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid optimization level: %v", s)
+	}
+	*l = level(v)
+	return nil
+}
+
+func (l *level) IsBoolFlag() bool {
+	return true
+}
+
 func langUsage() string {
-	return fmt.Sprintf("currently supported languages: %v; default %v",
+	return fmt.Sprintf("currently supported languages: %v, %v; "+
+		"default %v",
 		frontend.SML,
 		frontend.MYRMIDON,
 		frontend.SML)
@@ -40,67 +82,171 @@ func targetUsage() string {
 
 func init() {
 	flag.BoolVar(&pAST, "ast", false, "dump AST")
+	flag.BoolVar(&pASTJSON, "ast-json", false, "dump AST as JSON")
 	flag.BoolVar(&pASM, "asm", false, "dump pseudo assembly")
-	flag.BoolVar(&optimize, "O", false, "enable optimizer")
+	flag.BoolVar(&format, "fmt", false, "print source in canonical "+
+		"format")
+	flag.StringVar(&dot, "dot", "", "dump graphviz graph of the ast or "+
+		"of the control flow graph (cfg)")
+	flag.StringVar(&fromAST, "from-ast", "", "JSON AST file, replaces "+
+		"-i and -lang")
+	flag.Var(&optLevel, "O", "optimization level, -O means -O=1")
+	flag.StringVar(&passes, "passes", "", "comma separated optimizer "+
+		"passes, overrides -O; available: "+
+		strings.Join(optimizer.Passes(), ","))
+	flag.BoolVar(&stats, "stats", false, "print optimizer statistics")
+	flag.BoolVar(&diagJSON, "diag-json", false, "print errors as JSON "+
+		"diagnostics")
 	flag.StringVar(&lang, "lang", frontend.SML, langUsage())
 	flag.StringVar(&target, "target", backend.TVM, targetUsage())
 	flag.StringVar(&in, "i", "", "source file")
 	flag.StringVar(&out, "o", "-", "output file; default stdout")
 }
 
-func _main() error {
+// optimize runs the optimizer passes that were selected on the command line.
+func optimize(a ast.Node) (ast.Node, error) {
+	names, err := optimizer.Level(int(optLevel))
+	if err != nil {
+		return a, err
+	}
+	if passes != "" {
+		names = strings.Split(passes, ",")
+	}
+	p, err := optimizer.NewPipeline(names...)
+	if err != nil {
+		return a, err
+	}
+	ao, st, err := p.Run(a)
+	if err != nil {
+		return a, err
+	}
+	if stats {
+		for _, v := range st {
+			fmt.Fprintf(os.Stderr, "%v\n", v)
+		}
+	}
+	return ao, nil
+}
+
+// parse returns the AST and the comments of the source file or the AST of
+// the JSON AST file.
+func parse() (ast.Node, []ast.Comment, error) {
+	if fromAST != "" {
+		j, err := ioutil.ReadFile(fromAST)
+		if err != nil {
+			return ast.Node{}, nil, err
+		}
+		a, err := ast.UnmarshalJSON(j)
+		if err != nil {
+			return ast.Node{}, nil, fmt.Errorf("%v: %v", fromAST, err)
+		}
+		return a, nil, ast.Validate(a)
+	}
+
 	fe, err := frontend.New(lang)
 	if err != nil {
-		return err
+		return ast.Node{}, nil, err
 	}
 
 	// read source file
 	src, err := ioutil.ReadFile(in)
 	if err != nil {
-		return err
+		return ast.Node{}, nil, err
 	}
 
 	// Compile source
 	err = fe.Compile(string(src))
 	if err != nil {
-		return err
+		return ast.Node{}, nil, err
 	}
 
 	// obtains AST
 	a, err := fe.AST()
+	if err != nil {
+		return ast.Node{}, nil, err
+	}
+	comments, err := fe.Comments()
+	if err != nil {
+		return ast.Node{}, nil, err
+	}
+	return a, comments, nil
+}
+
+// output returns the writer of the output file.
+func output() (io.Writer, error) {
+	if out == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(out)
+}
+
+func _main() error {
+	a, comments, err := parse()
+	if err != nil {
+		return err
+	}
+
+	// reprint source, this does not need a target nor optimizations
+	if format {
+		w, err := output()
+		if err != nil {
+			return err
+		}
+		return ast.Format(a, w, comments)
+	}
+
+	// target determines which external functions can be called
+	t, err := backend.New(target)
+	if err != nil {
+		return err
+	}
+
+	// reject meaningless programs before they are transformed
+	err = semantic.Check(a, t.Externs()...)
+	if err != nil {
+		return err
+	}
+	a, err = semantic.TypeCheck(a, t.Externs()...)
 	if err != nil {
 		return err
 	}
 
 	// optimize AST
-	ao, err := optimizer.Optimize(a)
+	ao, err := optimize(a)
 	if err != nil {
 		return err
 	}
 
 	// dump AST pseudo asm
-	if pAST || pASM {
-		var w io.Writer
-		if out == "-" {
-			w = os.Stdout
-		} else {
-			w, err = os.Create(out)
+	if pAST || pASTJSON || pASM || dot != "" {
+		w, err := output()
+		if err != nil {
+			return err
+		}
+		switch dot {
+		case "":
+		case "ast":
+			return ast.DumpASTDot(ao, w)
+		case "cfg":
+			return ast.DumpCFGDot(ao, w, t.Externs()...)
+		default:
+			return fmt.Errorf("invalid graph: %v", dot)
+		}
+		if pASM {
+			return ast.DumpPseudoAsm(ao, w, t.Externs()...)
+		}
+		if pASTJSON {
+			j, err := ast.MarshalJSON(ao)
 			if err != nil {
 				return err
 			}
-		}
-		if pASM {
-			return ast.DumpPseudoAsm(ao, w)
+			_, err = fmt.Fprintf(w, "%s\n", j)
+			return err
 		}
 		return ast.DumpAST(ao, w)
 	}
 
 	// obtain binary image
-	// XXX this New must be done much earlier
-	t, err := backend.New(target)
-	if err != nil {
-		return err
-	}
 	bi, err := t.EmitCode(ao)
 	if err != nil {
 		return err
@@ -119,6 +265,28 @@ func _main() error {
 	return nil
 }
 
+// printError prints the diagnostics in err.
+// Diagnostics are printed with the offending source line underlined or as a
+// JSON array when -diag-json is set.
+func printError(err error) {
+	l := diagnostics.FromError(err, diagnostics.Unknown)
+	if diagJSON {
+		j, err := json.MarshalIndent(l, "", "\t")
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+	for _, d := range l {
+		fmt.Printf("%v\n", d)
+		if c := d.Context(); c != "" {
+			fmt.Printf("%v\n", c)
+		}
+	}
+}
+
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
@@ -126,15 +294,15 @@ func main() {
 	flag.Parse()
 
 	// check required flags
-	if in == "" {
+	if in == "" && fromAST == "" {
 		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\n-i must be provided\n")
+		fmt.Fprintf(os.Stderr, "\n-i or -from-ast must be provided\n")
 		os.Exit(1)
 	}
 
 	err := _main()
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		printError(err)
 		os.Exit(1)
 	}
 }
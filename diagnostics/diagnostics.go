@@ -0,0 +1,198 @@
+// diagnostics describes problems that are found by the compiler stages and
+// the virtual machine in a uniform, machine readable, way.
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Severity indicates how bad a diagnostic is.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+var severities = map[Severity]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+}
+
+func (s Severity) String() string {
+	if name, found := severities[s]; found {
+		return name
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// MarshalJSON encodes a severity as its name.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes a severity from its name.
+func (s *Severity) UnmarshalJSON(b []byte) error {
+	var name string
+	err := json.Unmarshal(b, &name)
+	if err != nil {
+		return err
+	}
+	for k, v := range severities {
+		if v == name {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", name)
+}
+
+// Code identifies the kind of problem that a diagnostic describes.
+type Code string
+
+const (
+	// frontends
+	Syntax Code = "syntax" // source does not parse
+
+	// AST consumers
+	InvalidAST        Code = "invalid-ast"        // malformed AST
+	DuplicateIdent    Code = "duplicate-ident"    // identifier listed twice
+	DuplicateFunction Code = "duplicate-function" // function redefined
+	UndefinedFunction Code = "undefined-function" // call to unknown function
+	ArgumentCount     Code = "argument-count"     // wrong number of arguments
+	ResultCount       Code = "result-count"       // wrong number of results
+	ArgumentType      Code = "argument-type"      // argument of wrong type
+	InvalidMain       Code = "invalid-main"       // main has a signature
+	ShadowedParam     Code = "shadowed-param"     // result shadows parameter
+	InvalidGlobal     Code = "invalid-global"     // illegal global declaration
+	UndefinedVariable Code = "undefined-variable" // use before assignment
+	TypeMismatch      Code = "type-mismatch"      // value of the wrong type
+	InvalidOperation  Code = "invalid-operation"  // operator on wrong types
+
+	// backends
+	MissingMain    Code = "missing-main"    // program has no main function
+	DuplicateLabel Code = "duplicate-label" // label emitted twice
+	Internal       Code = "internal"        // compiler bug
+
+	// virtual machine
+	Runtime Code = "runtime" // program aborted while running
+
+	// other
+	Unknown Code = "unknown" // error that is not a diagnostic
+)
+
+// Pos is a location in the source.
+// The zero value is an unknown location.
+type Pos struct {
+	LineNo   int    `json:"lineNo"`
+	ColStart int    `json:"colStart"`
+	ColEnd   int    `json:"colEnd"`
+	Line     string `json:"line,omitempty"` // source line
+}
+
+// IsValid returns true if the location is known.
+func (p Pos) IsValid() bool {
+	return p.LineNo > 0
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("line %v,%v-%v", p.LineNo, p.ColStart, p.ColEnd)
+}
+
+// Diagnostic is a problem at a location in the source.
+// It implements the error interface.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Pos      Pos      `json:"pos"`
+	Code     Code     `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// Errorf returns an error diagnostic at pos.
+func Errorf(pos Pos, code Code, format string,
+	args ...interface{}) *Diagnostic {
+
+	return &Diagnostic{
+		Severity: Error,
+		Pos:      pos,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Error returns the diagnostic in the traditional line,column format.
+// Errors are not prefixed with their severity.
+func (d *Diagnostic) Error() string {
+	s := d.Message
+	if d.Severity != Error {
+		s = d.Severity.String() + ": " + s
+	}
+	if d.Pos.IsValid() {
+		s = d.Pos.String() + ": " + s
+	}
+	return s
+}
+
+// Context returns the source line of the diagnostic with a caret underline
+// below the offending columns.
+// Tabs are retained in the underline so that it lines up with the source.
+func (d *Diagnostic) Context() string {
+	line := strings.TrimRight(d.Pos.Line, "\r\n")
+	if line == "" {
+		return ""
+	}
+	var u []byte
+	for k := 0; k < d.Pos.ColStart-1 && k < len(line); k++ {
+		if line[k] == '\t' {
+			u = append(u, '\t')
+		} else {
+			u = append(u, ' ')
+		}
+	}
+	for k := d.Pos.ColStart; k < d.Pos.ColEnd || k == d.Pos.ColStart; k++ {
+		u = append(u, '^')
+	}
+	return line + "\n" + string(u)
+}
+
+// List is a list of diagnostics in the order they were found.
+// It implements the error interface.
+type List []*Diagnostic
+
+// Error returns all diagnostics, one per line.
+func (l List) Error() string {
+	s := make([]string, 0, len(l))
+	for _, d := range l {
+		s = append(s, d.Error())
+	}
+	return strings.Join(s, "\n")
+}
+
+// Errors returns true if l contains at least one error.
+func (l List) Errors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// FromError returns the diagnostics that are contained in err.
+// Errors that are not diagnostics are converted to a diagnostic without
+// location and with code.
+func FromError(err error, code Code) List {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case List:
+		return e
+	case *Diagnostic:
+		return List{e}
+	}
+	return List{{Severity: Error, Code: code, Message: err.Error()}}
+}
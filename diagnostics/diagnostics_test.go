@@ -0,0 +1,98 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		d    *Diagnostic
+		want string
+	}{
+		{
+			d:    Errorf(Pos{}, MissingMain, "function main not found"),
+			want: "function main not found",
+		},
+		{
+			d: Errorf(Pos{LineNo: 2, ColStart: 3, ColEnd: 5},
+				Syntax, "syntax error: unexpected %v", "ASSIGN"),
+			want: "line 2,3-5: syntax error: unexpected ASSIGN",
+		},
+		{
+			d: &Diagnostic{
+				Severity: Warning,
+				Pos:      Pos{LineNo: 1, ColStart: 1, ColEnd: 2},
+				Message:  "unused",
+			},
+			want: "line 1,1-2: warning: unused",
+		},
+	}
+	for k, v := range tests {
+		if got := v.d.Error(); got != v.want {
+			t.Fatalf("%v: got %q, want %q", k, got, v.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	d := Errorf(Pos{
+		LineNo:   4,
+		ColStart: 7,
+		ColEnd:   8,
+		Line:     "\t\ty = = 2;\n",
+	}, Syntax, "syntax error")
+	want := "\t\ty = = 2;\n\t\t    ^"
+	if got := d.Context(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if got := Errorf(Pos{}, Syntax, "x").Context(); got != "" {
+		t.Fatalf("context without source line: %q", got)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	d := Errorf(Pos{}, Runtime, "divide by 0")
+	l := List{d, d}
+	if got := FromError(l, Unknown); !reflect.DeepEqual(got, l) {
+		t.Fatalf("list: %v", got)
+	}
+	if got := FromError(d, Unknown); !reflect.DeepEqual(got, List{d}) {
+		t.Fatalf("diagnostic: %v", got)
+	}
+	got := FromError(errors.New("no such file"), Unknown)
+	if len(got) != 1 || got[0].Code != Unknown ||
+		got[0].Message != "no such file" {
+		t.Fatalf("error: %v", got)
+	}
+	if FromError(nil, Unknown) != nil {
+		t.Fatalf("nil error")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	l := List{
+		Errorf(Pos{LineNo: 1, ColStart: 2, ColEnd: 3}, Syntax, "a"),
+		{Severity: Note, Code: Unknown, Message: "b"},
+	}
+	j, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l2 List
+	err = json.Unmarshal(j, &l2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(l, l2) {
+		t.Fatalf("got %v, want %v", l2, l)
+	}
+
+	var s Severity
+	if json.Unmarshal([]byte(`"fatal"`), &s) == nil {
+		t.Fatalf("expected unknown severity error")
+	}
+}
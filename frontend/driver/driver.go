@@ -15,6 +15,8 @@ type Frontend interface {
 	AST() (ast.Node, error)   // return AST of compiled code
 	Lines() ([]string, error) // return the script in array of strings
 	Line(int) (string, error) // return an individual line of script
+
+	Comments() ([]ast.Comment, error) // return comments of the script
 }
 
 // LineGenerator slices the source file up in individual lines.
@@ -28,10 +30,14 @@ func LineGenerator(src string) ([]string, error) {
 	for {
 		line, err := r.ReadBytes('\n')
 		if err != nil {
-			if err == io.EOF {
-				break
+			if err != io.EOF {
+				return nil, err
+			}
+			// last line without a newline
+			if len(line) != 0 {
+				lines = append(lines, string(line))
 			}
-			return nil, err
+			break
 		}
 		lines = append(lines, string(line))
 	}
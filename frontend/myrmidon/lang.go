@@ -1,9 +1,13 @@
+// Code generated by goyacc -o lang.go.u lang.y. DO NOT EDIT.
+
 //line lang.y:2
+
 package myrmidon
 
 import __yyfmt__ "fmt"
 
 //line lang.y:3
+
 import (
 	"github.com/marcopeereboom/gck/ast"
 	"math/big"
@@ -17,7 +21,9 @@ type yySymType struct {
 	integer    int
 	number     *big.Rat
 	identifier string
+	str        string
 	node       ast.Node
+	pos        *ast.NodeDebugInformation // location of a token
 }
 
 const PROGRAM = 57346
@@ -32,15 +38,26 @@ const IF = 57354
 const ELSE = 57355
 const EOL = 57356
 const ASSIGN = 57357
-const LE = 57358
-const GE = 57359
-const NE = 57360
-const EQ = 57361
-const LT = 57362
-const GT = 57363
-const UMINUS = 57364
-
-var yyToknames = []string{
+const STRING = 57358
+const LEN = 57359
+const TRUE = 57360
+const FALSE = 57361
+const AND = 57362
+const OR = 57363
+const NOT = 57364
+const GLOBAL = 57365
+const LE = 57366
+const GE = 57367
+const NE = 57368
+const EQ = 57369
+const LT = 57370
+const GT = 57371
+const UMINUS = 57372
+
+var yyToknames = [...]string{
+	"$end",
+	"error",
+	"$unk",
 	"PROGRAM",
 	"INTEGER",
 	"IDENTIFIER",
@@ -53,6 +70,14 @@ var yyToknames = []string{
 	"ELSE",
 	"EOL",
 	"ASSIGN",
+	"STRING",
+	"LEN",
+	"TRUE",
+	"FALSE",
+	"AND",
+	"OR",
+	"NOT",
+	"GLOBAL",
 	"LE",
 	"GE",
 	"NE",
@@ -64,143 +89,210 @@ var yyToknames = []string{
 	"'*'",
 	"'/'",
 	"UMINUS",
+	"';'",
+	"'{'",
+	"'}'",
+	"'('",
+	"')'",
+	"'.'",
+	"','",
 }
-var yyStatenames = []string{}
+
+var yyStatenames = [...]string{}
 
 const yyEofCode = 1
 const yyErrCode = 2
-const yyMaxDepth = 200
+const yyInitialStackSize = 16
 
-//line lang.y:123
+//line lang.y:182
 
 //line yacctab:1
-var yyExca = []int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 19,
+	37, 12,
+	-2, 0,
 }
 
-const yyNprod = 40
 const yyPrivate = 57344
 
-var yyTokenNames []string
-var yyStates []string
-
-const yyLast = 137
-
-var yyAct = []int{
-
-	20, 19, 16, 41, 70, 32, 33, 34, 35, 49,
-	63, 11, 55, 56, 57, 58, 53, 54, 32, 33,
-	34, 35, 10, 8, 37, 9, 12, 51, 38, 40,
-	42, 42, 44, 7, 62, 45, 46, 47, 48, 36,
-	50, 14, 52, 34, 35, 61, 60, 59, 32, 33,
-	34, 35, 72, 31, 4, 29, 64, 65, 66, 67,
-	68, 69, 22, 24, 6, 28, 1, 23, 27, 28,
-	22, 24, 21, 73, 74, 23, 27, 28, 2, 3,
-	25, 12, 5, 17, 15, 12, 30, 26, 25, 22,
-	39, 71, 15, 12, 23, 26, 32, 33, 34, 35,
-	18, 22, 39, 13, 0, 51, 23, 25, 32, 33,
-	34, 35, 0, 0, 26, 0, 0, 0, 0, 25,
-	0, 0, 0, 0, 0, 0, 43, 55, 56, 57,
-	58, 53, 54, 32, 33, 34, 35,
+const yyLast = 285
+
+var yyAct = [...]int8{
+	23, 27, 26, 90, 88, 37, 104, 65, 71, 63,
+	13, 64, 114, 13, 103, 17, 58, 59, 12, 18,
+	54, 55, 56, 57, 52, 53, 48, 49, 50, 51,
+	63, 19, 64, 66, 72, 102, 105, 62, 14, 67,
+	69, 70, 8, 73, 74, 60, 43, 75, 107, 76,
+	77, 78, 79, 80, 81, 82, 83, 84, 85, 86,
+	87, 60, 10, 89, 92, 21, 94, 50, 51, 109,
+	19, 48, 49, 50, 51, 100, 101, 97, 9, 4,
+	99, 46, 98, 95, 30, 36, 44, 93, 11, 31,
+	42, 43, 61, 16, 15, 32, 35, 33, 34, 7,
+	3, 39, 29, 5, 1, 110, 92, 41, 91, 111,
+	38, 112, 113, 2, 22, 19, 45, 40, 28, 6,
+	24, 30, 36, 108, 25, 20, 31, 42, 43, 0,
+	0, 0, 32, 35, 33, 34, 0, 0, 39, 29,
+	0, 0, 0, 0, 0, 0, 0, 38, 0, 0,
+	0, 22, 19, 0, 40, 58, 59, 0, 0, 54,
+	55, 56, 57, 52, 53, 48, 49, 50, 51, 0,
+	0, 58, 59, 0, 96, 54, 55, 56, 57, 52,
+	53, 48, 49, 50, 51, 30, 68, 19, 0, 0,
+	31, 0, 0, 0, 0, 0, 32, 35, 33, 34,
+	0, 0, 39, 0, 0, 0, 0, 0, 0, 0,
+	0, 38, 0, 0, 0, 58, 59, 0, 40, 54,
+	55, 56, 57, 52, 53, 48, 49, 50, 51, 0,
+	106, 58, 59, 0, 0, 54, 55, 56, 57, 52,
+	53, 48, 49, 50, 51, 0, 47, 58, 59, 0,
+	0, 54, 55, 56, 57, 52, 53, 48, 49, 50,
+	51, 58, 0, 0, 0, 54, 55, 56, 57, 52,
+	53, 48, 49, 50, 51, 54, 55, 56, 57, 52,
+	53, 48, 49, 50, 51,
 }
-var yyPact = []int{
-
-	45, -1000, 45, -1000, 58, -1000, 3, -8, -5, -9,
-	-2, -1000, 65, 57, -1000, -1000, 26, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, 9, 84, 84, 96, 96, -1000,
-	-1000, -1000, 84, 84, 84, 84, -22, 84, -1000, -1000,
-	74, -2, 111, 96, -2, 19, 19, -1000, -1000, 7,
-	-17, -1000, -1000, 84, 84, 84, 84, 84, 84, -27,
-	-4, 39, -1000, -1000, 86, 86, 86, 86, 86, 86,
-	-1000, -1000, 53, -1000, -1000,
+
+var yyPact = [...]int16{
+	70, -1000, 70, -1000, 93, -1000, 4, -1000, 82, -21,
+	-28, -1000, 0, 88, 82, -1000, -24, -5, -1000, 116,
+	79, -1000, -1000, 211, -1000, -1000, -1000, -1000, 26, 82,
+	-1000, -1000, -1000, -1000, -1000, -1, -8, -1000, 180, 180,
+	180, -7, 180, 180, -1000, -1000, 10, -1000, 180, 180,
+	180, 180, 180, 180, 180, 180, 180, 180, 180, 180,
+	-1000, -31, 180, 180, 81, 180, 77, -1000, -29, -1000,
+	135, 76, 74, 151, 151, -1000, 35, 35, -1000, -1000,
+	41, 41, 41, 41, 41, 41, 251, 241, -1000, -4,
+	-25, -35, 227, -2, 195, -1000, -1000, 13, -29, -1000,
+	-1000, 56, -1000, -1000, 180, 180, -1000, -1000, -1000, 34,
+	227, -27, -1000, -1000, -1000,
 }
-var yyPgo = []int{
 
-	0, 41, 103, 2, 3, 100, 1, 91, 0, 83,
-	79, 78, 72, 66,
+var yyPgo = [...]int8{
+	0, 65, 125, 0, 124, 2, 123, 1, 120, 100,
+	119, 113, 5, 78, 62, 3, 108, 107, 104,
 }
-var yyR1 = []int{
 
-	0, 13, 11, 11, 1, 1, 1, 1, 1, 1,
-	1, 2, 2, 2, 8, 12, 10, 9, 9, 5,
-	6, 7, 7, 7, 4, 4, 4, 4, 4, 4,
-	4, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+var yyR1 = [...]int8{
+	0, 18, 11, 11, 1, 1, 1, 1, 1, 1,
+	1, 1, 2, 2, 2, 7, 7, 12, 12, 15,
+	15, 16, 16, 9, 10, 13, 13, 14, 14, 8,
+	8, 17, 17, 4, 5, 6, 6, 6, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3,
 }
-var yyR2 = []int{
 
+var yyR2 = [...]int8{
 	0, 1, 1, 2, 1, 2, 1, 1, 1, 1,
-	1, 0, 1, 2, 3, 4, 7, 4, 1, 3,
-	4, 0, 2, 2, 3, 3, 3, 3, 3, 3,
-	3, 1, 1, 1, 2, 3, 3, 3, 3, 3,
+	2, 3, 0, 1, 2, 3, 4, 4, 6, 0,
+	1, 1, 3, 9, 1, 0, 1, 1, 3, 4,
+	4, 3, 3, 3, 4, 0, 2, 2, 1, 1,
+	1, 1, 1, 4, 1, 1, 2, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 2,
+	3,
 }
-var yyChk = []int{
-
-	-1000, -13, -11, -10, 9, -10, 6, 30, 31, 30,
-	31, -8, 28, -2, -1, 27, -3, -9, -5, -6,
-	-8, -12, 5, 10, 6, 23, 30, 11, 12, -1,
-	29, 27, 22, 23, 24, 25, 30, 15, -3, 6,
-	-3, -4, -3, 30, -4, -3, -3, -3, -3, 31,
-	-3, 31, -8, 20, 21, 16, 17, 18, 19, -4,
-	-3, -8, 27, 27, -3, -3, -3, -3, -3, -3,
-	31, -7, 13, -8, -6,
+
+var yyChk = [...]int16{
+	-1000, -18, -11, -9, 9, -9, -10, 6, 38, -13,
+	-14, 6, 39, 41, 38, 6, -13, 39, -7, 36,
+	-2, -1, 35, -3, -8, -4, -5, -7, 2, 23,
+	5, 10, 16, 18, 19, 17, 6, -12, 31, 22,
+	38, -17, 11, 12, -1, 37, 2, 35, 30, 31,
+	32, 33, 28, 29, 24, 25, 26, 27, 20, 21,
+	35, -14, 38, 38, 40, 15, 41, -3, 6, -3,
+	-3, 15, 41, -3, -3, 37, -3, -3, -3, -3,
+	-3, -3, -3, -3, -3, -3, -3, -3, 35, -3,
+	-15, -16, -3, 6, -3, 6, 39, -12, 6, 6,
+	-7, -7, 39, 39, 41, 38, 35, 35, -6, 13,
+	-3, -15, -7, -5, 39,
 }
-var yyDef = []int{
-
-	0, -2, 1, 2, 0, 3, 0, 0, 0, 0,
-	0, 16, 11, 0, 12, 4, 0, 6, 7, 8,
-	9, 10, 31, 32, 33, 0, 0, 0, 0, 13,
-	14, 5, 0, 0, 0, 0, 0, 0, 34, 33,
-	0, 0, 0, 0, 0, 35, 36, 37, 38, 0,
-	0, 39, 19, 0, 0, 0, 0, 0, 0, 0,
-	0, 21, 15, 17, 24, 25, 26, 27, 28, 29,
-	30, 20, 0, 22, 23,
+
+var yyDef = [...]int8{
+	0, -2, 1, 2, 0, 3, 0, 24, 25, 0,
+	26, 27, 0, 0, 25, 28, 0, 0, 23, -2,
+	0, 13, 4, 0, 6, 7, 8, 9, 0, 0,
+	38, 39, 40, 41, 42, 0, 44, 45, 0, 0,
+	0, 0, 0, 0, 14, 15, 0, 5, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	10, 0, 0, 19, 0, 0, 0, 46, 44, 59,
+	0, 0, 0, 0, 0, 16, 47, 48, 49, 50,
+	51, 52, 53, 54, 55, 56, 57, 58, 11, 0,
+	0, 20, 21, 0, 0, 31, 60, 0, 0, 32,
+	33, 35, 43, 17, 0, 19, 29, 30, 34, 0,
+	22, 0, 36, 37, 18,
 }
-var yyTok1 = []int{
 
+var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	30, 31, 24, 22, 3, 23, 3, 25, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 27,
+	38, 39, 32, 30, 41, 31, 40, 33, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 35,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 28, 3, 29,
+	3, 3, 3, 36, 3, 37,
 }
-var yyTok2 = []int{
 
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	26,
+	22, 23, 24, 25, 26, 27, 28, 29, 34,
 }
-var yyTok3 = []int{
+
+var yyTok3 = [...]int8{
 	0,
 }
 
+var yyErrorMessages = [...]struct {
+	state int
+	token int
+	msg   string
+}{}
+
 //line yaccpar:1
 
 /*	parser for yacc output	*/
 
-var yyDebug = 0
+var (
+	yyDebug        = 0
+	yyErrorVerbose = false
+)
 
 type yyLexer interface {
 	Lex(lval *yySymType) int
 	Error(s string)
 }
 
+type yyParser interface {
+	Parse(yyLexer) int
+	Lookahead() int
+}
+
+type yyParserImpl struct {
+	lval  yySymType
+	stack [yyInitialStackSize]yySymType
+	char  int
+}
+
+func (p *yyParserImpl) Lookahead() int {
+	return p.char
+}
+
+func yyNewParser() yyParser {
+	return &yyParserImpl{}
+}
+
 const yyFlag = -1000
 
 func yyTokname(c int) string {
-	// 4 is TOKSTART above
-	if c >= 4 && c-4 < len(yyToknames) {
-		if yyToknames[c-4] != "" {
-			return yyToknames[c-4]
+	if c >= 1 && c-1 < len(yyToknames) {
+		if yyToknames[c-1] != "" {
+			return yyToknames[c-1]
 		}
 	}
 	return __yyfmt__.Sprintf("tok-%v", c)
@@ -215,51 +307,127 @@ func yyStatname(s int) string {
 	return __yyfmt__.Sprintf("state-%v", s)
 }
 
-func yylex1(lex yyLexer, lval *yySymType) int {
-	c := 0
-	char := lex.Lex(lval)
+func yyErrorMessage(state, lookAhead int) string {
+	const TOKSTART = 4
+
+	if !yyErrorVerbose {
+		return "syntax error"
+	}
+
+	for _, e := range yyErrorMessages {
+		if e.state == state && e.token == lookAhead {
+			return "syntax error: " + e.msg
+		}
+	}
+
+	res := "syntax error: unexpected " + yyTokname(lookAhead)
+
+	// To match Bison, suggest at most four expected tokens.
+	expected := make([]int, 0, 4)
+
+	// Look for shiftable tokens.
+	base := int(yyPact[state])
+	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+	}
+
+	if yyDef[state] == -2 {
+		i := 0
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
+			i += 2
+		}
+
+		// Look for tokens that we accept or reduce.
+		for i += 2; yyExca[i] >= 0; i += 2 {
+			tok := int(yyExca[i])
+			if tok < TOKSTART || yyExca[i+1] == 0 {
+				continue
+			}
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+
+		// If the default action is to accept or reduce, give up.
+		if yyExca[i+1] != 0 {
+			return res
+		}
+	}
+
+	for i, tok := range expected {
+		if i == 0 {
+			res += ", expecting "
+		} else {
+			res += " or "
+		}
+		res += yyTokname(tok)
+	}
+	return res
+}
+
+func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
+	token = 0
+	char = lex.Lex(lval)
 	if char <= 0 {
-		c = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		c = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			c = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		c = yyTok3[i+0]
-		if c == char {
-			c = yyTok3[i+1]
+		token = int(yyTok3[i+0])
+		if token == char {
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
-	if c == 0 {
-		c = yyTok2[1] /* unknown char */
+	if token == 0 {
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
-		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(c), uint(char))
+		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
 	}
-	return c
+	return char, token
 }
 
 func yyParse(yylex yyLexer) int {
+	return yyNewParser().Parse(yylex)
+}
+
+func (yyrcvr *yyParserImpl) Parse(yylex yyLexer) int {
 	var yyn int
-	var yylval yySymType
 	var yyVAL yySymType
-	yyS := make([]yySymType, yyMaxDepth)
+	var yyDollar []yySymType
+	_ = yyDollar // silence set and not used
+	yyS := yyrcvr.stack[:]
 
 	Nerrs := 0   /* number of errors */
 	Errflag := 0 /* error recovery flag */
 	yystate := 0
-	yychar := -1
+	yyrcvr.char = -1
+	yytoken := -1 // yyrcvr.char translated into internal numbering
+	defer func() {
+		// Make sure we report no lookahead when not parsing.
+		yystate = -1
+		yyrcvr.char = -1
+		yytoken = -1
+	}()
 	yyp := -1
 	goto yystack
 
@@ -272,7 +440,7 @@ ret1:
 yystack:
 	/* put a state and value onto the stack */
 	if yyDebug >= 4 {
-		__yyfmt__.Printf("char %v in %v\n", yyTokname(yychar), yyStatname(yystate))
+		__yyfmt__.Printf("char %v in %v\n", yyTokname(yytoken), yyStatname(yystate))
 	}
 
 	yyp++
@@ -285,21 +453,22 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
-	if yychar < 0 {
-		yychar = yylex1(yylex, &yylval)
+	if yyrcvr.char < 0 {
+		yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
 	}
-	yyn += yychar
+	yyn += yytoken
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yychar { /* valid shift */
-		yychar = -1
-		yyVAL = yylval
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
+		yyrcvr.char = -1
+		yytoken = -1
+		yyVAL = yyrcvr.lval
 		yystate = yyn
 		if Errflag > 0 {
 			Errflag--
@@ -309,27 +478,27 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
-		if yychar < 0 {
-			yychar = yylex1(yylex, &yylval)
+		if yyrcvr.char < 0 {
+			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
 		}
 
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
-			if yyn < 0 || yyn == yychar {
+			yyn = int(yyExca[xi+0])
+			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -338,11 +507,11 @@ yydefault:
 		/* error ... attempt to resume parsing */
 		switch Errflag {
 		case 0: /* brand new error */
-			yylex.Error("syntax error")
+			yylex.Error(yyErrorMessage(yystate, yytoken))
 			Nerrs++
 			if yyDebug >= 1 {
 				__yyfmt__.Printf("%s", yyStatname(yystate))
-				__yyfmt__.Printf(" saw %s\n", yyTokname(yychar))
+				__yyfmt__.Printf(" saw %s\n", yyTokname(yytoken))
 			}
 			fallthrough
 
@@ -351,10 +520,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -370,12 +539,13 @@ yydefault:
 
 		case 3: /* no shift yet; clobber input char */
 			if yyDebug >= 2 {
-				__yyfmt__.Printf("error recovery discards %s\n", yyTokname(yychar))
+				__yyfmt__.Printf("error recovery discards %s\n", yyTokname(yytoken))
 			}
-			if yychar == yyEofCode {
+			if yytoken == yyEofCode {
 				goto ret1
 			}
-			yychar = -1
+			yyrcvr.char = -1
+			yytoken = -1
 			goto yynewstate /* try again in the same state */
 		}
 	}
@@ -389,219 +559,402 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
+	// yyp is now the index of $0. Perform the default action. Iff the
+	// reduced production is ε, $1 is possibly out of range.
+	if yyp+1 >= len(yyS) {
+		nyys := make([]yySymType, len(yyS)*2)
+		copy(nyys, yyS)
+		yyS = nyys
+	}
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
 	switch yynt {
 
 	case 1:
-		//line lang.y:49
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:63
 		{
-			d.tree = yyS[yypt-0].node
+			d.tree = yyDollar[1].node
 		}
 	case 2:
-		//line lang.y:53
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:67
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 3:
-		//line lang.y:54
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:68
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(yyDollar[1].node.Debug, ast.Eos, yyDollar[1].node, yyDollar[2].node)
 		}
 	case 4:
-		//line lang.y:58
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:72
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos)
+			yyVAL.node = ast.NewOperand(yyDollar[1].pos, ast.Eos)
 		}
 	case 5:
-		//line lang.y:59
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:73
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = ast.NewDiscard(yyDollar[1].node.Debug, yyDollar[1].node)
 		}
 	case 6:
-		//line lang.y:60
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:74
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 7:
-		//line lang.y:61
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:75
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 8:
-		//line lang.y:62
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:76
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 9:
-		//line lang.y:63
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:77
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 10:
-		//line lang.y:64
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:78
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewOperand(yyDollar[2].pos, ast.Eos)
 		}
 	case 11:
-		//line lang.y:68
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:79
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos)
+			yyVAL.node = ast.NewGlobal(yyDollar[1].pos, ast.Nodes(yyDollar[2].node))
 		}
 	case 12:
-		//line lang.y:69
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:83
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewOperand(nil, ast.Eos)
 		}
 	case 13:
-		//line lang.y:70
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:84
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[1].node
 		}
 	case 14:
-		//line lang.y:74
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:85
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = ast.NewOperand(yyDollar[1].node.Debug, ast.Eos, yyDollar[1].node, yyDollar[2].node)
 		}
 	case 15:
-		//line lang.y:78
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:89
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.FunctionCall, ast.NewIdentifier(nil, yyS[yypt-3].identifier))
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[3].pos
 		}
 	case 16:
-		//line lang.y:82
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:90
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Function, ast.NewIdentifier(nil, yyS[yypt-5].identifier), yyS[yypt-0].node)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[4].pos
 		}
 	case 17:
-		//line lang.y:86
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:94
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Assign, ast.NewIdentifier(nil, yyS[yypt-3].identifier), yyS[yypt-1].node)
+			yyVAL.node = ast.NewCall(yyDollar[1].pos, yyDollar[1].identifier, ast.Nodes(yyDollar[3].node))
 		}
 	case 18:
-		//line lang.y:87
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line lang.y:95
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewCall(span(yyDollar[1].pos, yyDollar[3].pos), yyDollar[1].identifier+"."+yyDollar[3].identifier, ast.Nodes(yyDollar[5].node))
 		}
 	case 19:
-		//line lang.y:91
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:99
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.While, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(nil, ast.List)
 		}
 	case 20:
-		//line lang.y:94
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:100
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.If, yyS[yypt-2].node, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[1].node
 		}
 	case 21:
-		//line lang.y:97
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:104
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos)
+			yyVAL.node = ast.NewOperand(yyDollar[1].node.Debug, ast.List, yyDollar[1].node)
 		}
 	case 22:
-		//line lang.y:98
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:105
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.Append(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 23:
-		//line lang.y:99
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line lang.y:109
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewFunc(span(yyDollar[2].node.Debug, yyDollar[9].pos), yyDollar[2].node.Value.(ast.NodeIdentifier).Value, ast.Nodes(yyDollar[4].node), ast.Nodes(yyDollar[7].node), yyDollar[9].node)
 		}
 	case 24:
-		//line lang.y:103
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:113
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Lt, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier)
 		}
 	case 25:
-		//line lang.y:104
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:117
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Gt, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(nil, ast.List)
 		}
 	case 26:
-		//line lang.y:105
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:118
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Le, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[1].node
 		}
 	case 27:
-		//line lang.y:106
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:122
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Ge, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(yyDollar[1].pos, ast.List, ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier))
 		}
 	case 28:
-		//line lang.y:107
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:123
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Ne, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.Append(yyDollar[1].node, ast.NewIdentifier(yyDollar[3].pos, yyDollar[3].identifier))
 		}
 	case 29:
-		//line lang.y:108
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:127
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eq, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewAssign(yyDollar[1].pos, []ast.Node{ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier)}, yyDollar[3].node)
 		}
 	case 30:
-		//line lang.y:109
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:128
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = ast.NewAssign(yyDollar[1].node.Debug, ast.Nodes(yyDollar[1].node), yyDollar[3].node)
 		}
 	case 31:
-		//line lang.y:113
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:132
 		{
-			yyVAL.node = ast.NewInteger(d.d(), yyS[yypt-0].integer)
+			yyVAL.node = ast.NewOperand(yyDollar[1].pos, ast.List, ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier), ast.NewIdentifier(yyDollar[3].pos, yyDollar[3].identifier))
 		}
 	case 32:
-		//line lang.y:114
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:133
 		{
-			yyVAL.node = ast.NewNumber(d.d(), yyS[yypt-0].number)
+			yyVAL.node = ast.Append(yyDollar[1].node, ast.NewIdentifier(yyDollar[3].pos, yyDollar[3].identifier))
 		}
 	case 33:
-		//line lang.y:115
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:137
 		{
-			yyVAL.node = ast.NewIdentifier(d.d(), yyS[yypt-0].identifier)
+			yyVAL.node = ast.NewWhile(span(yyDollar[1].pos, yyDollar[3].pos), yyDollar[2].node, yyDollar[3].node)
 		}
 	case 34:
-		//line lang.y:116
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:141
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Uminus, yyS[yypt-0].node)
+			// the statement ends at the last closing brace
+			end := yyDollar[3].pos
+			if yyDollar[4].pos != nil {
+				end = yyDollar[4].pos
+			}
+			yyVAL.node = ast.NewIf(span(yyDollar[1].pos, end), yyDollar[2].node, yyDollar[3].node, yyDollar[4].node)
+			yyVAL.pos = end
 		}
 	case 35:
-		//line lang.y:117
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:152
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Add, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.Node{}
+			yyVAL.pos = nil
 		}
 	case 36:
-		//line lang.y:118
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:153
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Sub, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[2].pos
 		}
 	case 37:
-		//line lang.y:119
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:154
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Mul, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[2].pos
 		}
 	case 38:
-		//line lang.y:120
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:158
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Div, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewInteger(yyDollar[1].pos, yyDollar[1].integer)
 		}
 	case 39:
-		//line lang.y:121
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:159
+		{
+			yyVAL.node = ast.NewNumber(yyDollar[1].pos, yyDollar[1].number)
+		}
+	case 40:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:160
+		{
+			yyVAL.node = ast.NewString(yyDollar[1].pos, yyDollar[1].str)
+		}
+	case 41:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:161
+		{
+			yyVAL.node = ast.NewBool(yyDollar[1].pos, true)
+		}
+	case 42:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:162
+		{
+			yyVAL.node = ast.NewBool(yyDollar[1].pos, false)
+		}
+	case 43:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:163
+		{
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Len, yyDollar[3].node)
+		}
+	case 44:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:164
+		{
+			yyVAL.node = ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier)
+		}
+	case 45:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:165
+		{
+			yyVAL.node = yyDollar[1].node
+		}
+	case 46:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:166
+		{
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Uminus, yyDollar[2].node)
+		}
+	case 47:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:167
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Add, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 48:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:168
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Sub, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 49:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:169
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Mul, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 50:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:170
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Div, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 51:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:171
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Lt, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 52:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:172
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Gt, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 53:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:173
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Le, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 54:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:174
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Ge, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 55:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:175
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Ne, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 56:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:176
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Eq, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 57:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:177
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.And, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 58:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:178
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Or, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 59:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:179
+		{
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Not, yyDollar[2].node)
+		}
+	case 60:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:180
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = yyDollar[2].node
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -0,0 +1,244 @@
+package myrmidon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+// compile compiles src and returns the diagnostics.
+func compile(t *testing.T, src string) diagnostics.List {
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diagnostics.FromError(m.Compile(src), diagnostics.Unknown)
+}
+
+func TestInvalidEscape(t *testing.T) {
+	l := compile(t, "func main () () {\n"+
+		"\ts = \"a\\q\";\n"+
+		"\tx = ;\n"+
+		"}\n")
+	if len(l) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", l)
+	}
+	for k, line := range []int{2, 3} {
+		if l[k].Code != diagnostics.Syntax || l[k].Pos.LineNo != line {
+			t.Fatalf("invalid diagnostic %v: %v", k, l[k])
+		}
+	}
+	if l[0].Pos.ColStart != 6 {
+		t.Fatalf("invalid column %v", l[0].Pos)
+	}
+}
+
+func TestComments(t *testing.T) {
+	src := "// line\n" +
+		"func main () () {\n" +
+		"\tx = 1; /* block ** with * stars **/ y = 2;\n" +
+		"\t/* multi\n" +
+		"\t   line */ z = ;\n" +
+		"}\n"
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the error shows that lines and columns are counted past comments
+	l := diagnostics.FromError(m.Compile(src), diagnostics.Unknown)
+	if len(l) != 1 || l[0].Pos.LineNo != 5 || l[0].Pos.ColStart != 17 {
+		t.Fatalf("invalid diagnostics %v", l)
+	}
+
+	comments, err := m.Comments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ast.Comment{
+		{LineNo: 1, ColStart: 1, ColEnd: 8, Text: "// line"},
+		{LineNo: 3, ColStart: 9, ColEnd: 37,
+			Text: "/* block ** with * stars **/"},
+		{LineNo: 4, ColStart: 2, ColEnd: 12,
+			Text: "/* multi\n\t   line */"},
+	}
+	if !reflect.DeepEqual(comments, expected) {
+		t.Fatalf("invalid comments %+v", comments)
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := compile(t, "func main () () {\n"+
+		"\tx = 1; /* never\n"+
+		"\tclosed *\n")
+	if len(l) == 0 {
+		t.Fatal("expected unterminated block comment")
+	}
+	pos := diagnostics.Pos{
+		LineNo:   2,
+		ColStart: 9,
+		ColEnd:   11,
+		Line:     "\tx = 1; /* never\n",
+	}
+	if l[0].Code != diagnostics.Syntax || l[0].Pos != pos {
+		t.Fatalf("invalid diagnostic %v", l[0])
+	}
+}
+
+// assigned returns the names of the assigned variables in n in source order.
+func assigned(n ast.Node) []string {
+	var names []string
+	ast.Inspect(n, func(c ast.Node) bool {
+		if a, ok := c.Value.(ast.AssignStmt); ok {
+			for _, v := range a.Targets {
+				names = append(names,
+					v.Value.(ast.NodeIdentifier).Value)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+func TestRecovery(t *testing.T) {
+	src := "func main () () {\n" +
+		"\tx = ;\n" +
+		"\ty = 1;\n" +
+		"\tif y > 0 {\n" +
+		"\t\tz = 2 +;\n" +
+		"\t\tu = 5;\n" +
+		"\t}\n" +
+		"\tw = 3 3;\n" +
+		"\tv = 4;\n" +
+		"}\n"
+	tests := []struct {
+		name  string
+		src   string
+		pos   [][2]int // line and column of the errors
+		names []string // assigned variables
+	}{
+		{
+			name:  "statements and blocks",
+			src:   src,
+			pos:   [][2]int{{2, 6}, {5, 10}, {8, 8}},
+			names: []string{"y", "u", "v"},
+		},
+		{
+			name: "end of file",
+			src:  src + "func f () () {\n\ta = 1\n",
+			pos:  [][2]int{{2, 6}, {5, 10}, {8, 8}, {13, 1}},
+		},
+	}
+	for _, v := range tests {
+		m, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := diagnostics.FromError(m.Compile(v.src), diagnostics.Unknown)
+		if len(l) != len(v.pos) {
+			t.Fatalf("%v: expected %v diagnostics, got %v", v.name,
+				len(v.pos), l)
+		}
+		for k, d := range l {
+			if d.Code != diagnostics.Syntax ||
+				d.Pos.LineNo != v.pos[k][0] ||
+				d.Pos.ColStart != v.pos[k][1] {
+				t.Fatalf("%v: invalid diagnostic %v: %v", v.name, k,
+					d)
+			}
+		}
+		if v.names == nil {
+			continue
+		}
+
+		// parsing resumed after every error
+		a, err := m.AST()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := assigned(a); !reflect.DeepEqual(names, v.names) {
+			t.Fatalf("%v: invalid assignments %v", v.name, names)
+		}
+	}
+}
+
+// positions returns the location of the functions, identifiers, calls,
+// operators and while statements in n by name.
+func positions(n ast.Node) map[string]ast.NodeDebugInformation {
+	p := make(map[string]ast.NodeDebugInformation)
+	ast.Inspect(n, func(c ast.Node) bool {
+		name := ""
+		switch v := c.Value.(type) {
+		case ast.FuncDecl:
+			name = "func " + v.Name
+		case ast.NodeIdentifier:
+			name = v.Value
+		case ast.CallExpr:
+			name = v.Name + "()"
+		case ast.BinaryExpr:
+			name = "binary"
+		case ast.WhileNode:
+			name = "while"
+		}
+		if _, ok := p[name]; name != "" && !ok && c.Debug != nil {
+			d := *c.Debug
+			d.Line = ""
+			p[name] = d
+		}
+		return true
+	})
+	return p
+}
+
+func TestPositions(t *testing.T) {
+	src := "func main () () {\n" +
+		"\ty = q;\n" +
+		"\tnope(1);\n" +
+		"\tz = os.number(1) <= 2;\n" +
+		"\twhile z {\n" +
+		"\t\tz = false;\n" +
+		"\t}\n" +
+		"}\n"
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := m.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nodes are located at their own token and not at the lookahead
+	expected := map[string]ast.NodeDebugInformation{
+		"func main":   {LineNo: 1, ColStart: 6, ColEnd: 10, LineEnd: 8},
+		"y":           {LineNo: 2, ColStart: 2, ColEnd: 3},
+		"q":           {LineNo: 2, ColStart: 6, ColEnd: 7},
+		"nope()":      {LineNo: 3, ColStart: 2, ColEnd: 6},
+		"z":           {LineNo: 4, ColStart: 2, ColEnd: 3},
+		"os.number()": {LineNo: 4, ColStart: 6, ColEnd: 15},
+		"binary":      {LineNo: 4, ColStart: 19, ColEnd: 21},
+		"while":       {LineNo: 5, ColStart: 2, ColEnd: 7, LineEnd: 7},
+	}
+	if p := positions(a); !reflect.DeepEqual(p, expected) {
+		t.Fatalf("invalid positions %+v", p)
+	}
+
+	// single byte tokens end one past their column as well
+	l := compile(t, "func main () () {\n"+
+		"\tx = ;\n"+
+		"\ty = 1 <=;\n"+
+		"}\n")
+	for k, pos := range [][3]int{{2, 6, 7}, {3, 10, 11}} {
+		if len(l) <= k || l[k].Pos.LineNo != pos[0] ||
+			l[k].Pos.ColStart != pos[1] || l[k].Pos.ColEnd != pos[2] {
+			t.Fatalf("invalid diagnostics %v", l)
+		}
+	}
+}
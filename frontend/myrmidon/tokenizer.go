@@ -1,6 +1,8 @@
+// Code generated by golex. DO NOT EDIT.
+
 package myrmidon
 
-func (y *yylexer) Lex(val *yySymType) int {
+func (y *yylexer) lex(val *yySymType) int {
 	c := y.current
 	if y.empty {
 		c, y.empty = y.getc(), false
@@ -13,7 +15,6 @@ yystate0:
 
 	goto yystart1
 
-	goto yystate1 // silence unused label error
 yystate1:
 	c = y.getc()
 yystart1:
@@ -22,36 +23,50 @@ yystart1:
 		goto yyabort
 	case c == '!':
 		goto yystate4
-	case c == '.':
+	case c == '"':
 		goto yystate6
+	case c == '&':
+		goto yystate9
+	case c == '.':
+		goto yystate11
+	case c == '/':
+		goto yystate16
 	case c == '<':
-		goto yystate12
+		goto yystate22
 	case c == '=':
-		goto yystate14
+		goto yystate24
 	case c == '>':
-		goto yystate16
+		goto yystate26
 	case c == '\n' || c == '\r':
 		goto yystate3
 	case c == '\t' || c == ' ':
 		goto yystate2
 	case c == 'c':
-		goto yystate19
+		goto yystate29
 	case c == 'e':
-		goto yystate24
+		goto yystate34
 	case c == 'f':
-		goto yystate28
+		goto yystate38
+	case c == 'g':
+		goto yystate46
 	case c == 'i':
-		goto yystate32
+		goto yystate52
+	case c == 'l':
+		goto yystate54
 	case c == 'p':
-		goto yystate34
+		goto yystate57
+	case c == 't':
+		goto yystate64
 	case c == 'v':
-		goto yystate41
+		goto yystate68
 	case c == 'w':
-		goto yystate44
+		goto yystate71
+	case c == '|':
+		goto yystate76
 	case c >= '0' && c <= '9':
-		goto yystate11
-	case c >= 'A' && c <= 'Z' || c == 'a' || c == 'b' || c == 'd' || c == 'g' || c == 'h' || c >= 'j' && c <= 'o' || c >= 'q' && c <= 'u' || c >= 'x' && c <= 'z':
-		goto yystate18
+		goto yystate21
+	case c >= 'A' && c <= 'Z' || c == 'a' || c == 'b' || c == 'd' || c == 'h' || c == 'j' || c == 'k' || c >= 'm' && c <= 'o' || c >= 'q' && c <= 's' || c == 'u' || c >= 'x' && c <= 'z':
+		goto yystate28
 	}
 
 yystate2:
@@ -71,44 +86,39 @@ yystate4:
 	c = y.getc()
 	switch {
 	default:
-		goto yyabort
+		goto yyrule25
 	case c == '=':
 		goto yystate5
 	}
 
 yystate5:
 	c = y.getc()
-	goto yyrule14
+	goto yyrule21
 
 yystate6:
 	c = y.getc()
 	switch {
 	default:
 		goto yyabort
-	case c >= '0' && c <= '9':
+	case c == '"':
 		goto yystate7
+	case c == '\\':
+		goto yystate8
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= '!' || c >= '#' && c <= '[' || c >= ']' && c <= 'ÿ':
+		goto yystate6
 	}
 
 yystate7:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule19
-	case c == 'E' || c == 'e':
-		goto yystate8
-	case c >= '0' && c <= '9':
-		goto yystate7
-	}
+	goto yyrule32
 
 yystate8:
 	c = y.getc()
 	switch {
 	default:
 		goto yyabort
-	case c == '+' || c == '-':
-		goto yystate9
-	case c >= '0' && c <= '9':
-		goto yystate10
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= 'ÿ':
+		goto yystate6
 	}
 
 yystate9:
@@ -116,111 +126,122 @@ yystate9:
 	switch {
 	default:
 		goto yyabort
-	case c >= '0' && c <= '9':
+	case c == '&':
 		goto yystate10
 	}
 
 yystate10:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule19
-	case c >= '0' && c <= '9':
-		goto yystate10
-	}
+	goto yyrule23
 
 yystate11:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule18
-	case c == '.':
-		goto yystate7
-	case c == 'E' || c == 'e':
-		goto yystate8
+		goto yyrule27
 	case c >= '0' && c <= '9':
-		goto yystate11
+		goto yystate12
 	}
 
 yystate12:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule10
-	case c == '=':
+		goto yyrule31
+	case c == 'E' || c == 'e':
 		goto yystate13
+	case c >= '0' && c <= '9':
+		goto yystate12
 	}
 
 yystate13:
 	c = y.getc()
-	goto yyrule12
+	switch {
+	default:
+		goto yyabort
+	case c == '+' || c == '-':
+		goto yystate14
+	case c >= '0' && c <= '9':
+		goto yystate15
+	}
 
 yystate14:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule16
-	case c == '=':
+		goto yyabort
+	case c >= '0' && c <= '9':
 		goto yystate15
 	}
 
 yystate15:
 	c = y.getc()
-	goto yyrule15
+	switch {
+	default:
+		goto yyrule31
+	case c >= '0' && c <= '9':
+		goto yystate15
+	}
 
 yystate16:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule11
-	case c == '=':
+		goto yyrule28
+	case c == '*':
 		goto yystate17
+	case c == '/':
+		goto yystate20
 	}
 
 yystate17:
-	c = y.getc()
-	goto yyrule13
-
-yystate18:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yyrule5
+	case c == '*':
 		goto yystate18
+	case c >= '\x01' && c <= ')' || c >= '+' && c <= 'ÿ':
+		goto yystate17
 	}
 
-yystate19:
+yystate18:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'o':
-		goto yystate20
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
+		goto yyrule5
+	case c == '*':
 		goto yystate18
+	case c == '/':
+		goto yystate19
+	case c >= '\x01' && c <= ')' || c >= '+' && c <= '.' || c >= '0' && c <= 'ÿ':
+		goto yystate17
 	}
 
+yystate19:
+	c = y.getc()
+	goto yyrule4
+
 yystate20:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'n':
-		goto yystate21
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
-		goto yystate18
+		goto yyrule3
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= 'ÿ':
+		goto yystate20
 	}
 
 yystate21:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 's':
-		goto yystate22
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
-		goto yystate18
+		goto yyrule30
+	case c == '.':
+		goto yystate12
+	case c == 'E' || c == 'e':
+		goto yystate13
+	case c >= '0' && c <= '9':
+		goto yystate21
 	}
 
 yystate22:
@@ -228,114 +249,91 @@ yystate22:
 	switch {
 	default:
 		goto yyrule17
-	case c == 't':
+	case c == '=':
 		goto yystate23
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 's' || c >= 'u' && c <= 'z':
-		goto yystate18
 	}
 
 yystate23:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule5
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
-	}
+	goto yyrule19
 
 yystate24:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'l':
+		goto yyrule26
+	case c == '=':
 		goto yystate25
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
-		goto yystate18
 	}
 
 yystate25:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule17
-	case c == 's':
-		goto yystate26
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
-		goto yystate18
-	}
+	goto yyrule22
 
 yystate26:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'e':
+		goto yyrule18
+	case c == '=':
 		goto yystate27
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
-		goto yystate18
 	}
 
 yystate27:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule9
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
-	}
+	goto yyrule20
 
 yystate28:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'u':
-		goto yystate29
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 't' || c >= 'v' && c <= 'z':
-		goto yystate18
+		goto yyrule29
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
 	}
 
 yystate29:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'n':
+		goto yyrule29
+	case c == 'o':
 		goto yystate30
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
+		goto yystate28
 	}
 
 yystate30:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'c':
+		goto yyrule29
+	case c == 'n':
 		goto yystate31
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c == 'a' || c == 'b' || c >= 'd' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
+		goto yystate28
 	}
 
 yystate31:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule6
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yyrule29
+	case c == 's':
+		goto yystate32
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
+		goto yystate28
 	}
 
 yystate32:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'f':
+		goto yyrule29
+	case c == 't':
 		goto yystate33
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'e' || c >= 'g' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 's' || c >= 'u' && c <= 'z':
+		goto yystate28
 	}
 
 yystate33:
@@ -344,168 +342,466 @@ yystate33:
 	default:
 		goto yyrule8
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
 yystate34:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'r':
+		goto yyrule29
+	case c == 'l':
 		goto yystate35
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
+		goto yystate28
 	}
 
 yystate35:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'o':
+		goto yyrule29
+	case c == 's':
 		goto yystate36
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
+		goto yystate28
 	}
 
 yystate36:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'g':
+		goto yyrule29
+	case c == 'e':
 		goto yystate37
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'f' || c >= 'h' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
 	}
 
 yystate37:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'r':
-		goto yystate38
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
-		goto yystate18
+		goto yyrule16
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
 	}
 
 yystate38:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
+		goto yyrule29
 	case c == 'a':
 		goto yystate39
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
-		goto yystate18
+	case c == 'u':
+		goto yystate43
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 't' || c >= 'v' && c <= 'z':
+		goto yystate28
 	}
 
 yystate39:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'm':
+		goto yyrule29
+	case c == 'l':
 		goto yystate40
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'l' || c >= 'n' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
+		goto yystate28
 	}
 
 yystate40:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule3
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yyrule29
+	case c == 's':
+		goto yystate41
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
+		goto yystate28
 	}
 
 yystate41:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'a':
+		goto yyrule29
+	case c == 'e':
 		goto yystate42
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
 	}
 
 yystate42:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'r':
-		goto yystate43
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
-		goto yystate18
+		goto yyrule14
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
 	}
 
 yystate43:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule4
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yyrule29
+	case c == 'n':
+		goto yystate44
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
+		goto yystate28
 	}
 
 yystate44:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'h':
+		goto yyrule29
+	case c == 'c':
 		goto yystate45
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'g' || c >= 'i' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c == 'a' || c == 'b' || c >= 'd' && c <= 'z':
+		goto yystate28
 	}
 
 yystate45:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'i':
-		goto yystate46
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'h' || c >= 'j' && c <= 'z':
-		goto yystate18
+		goto yyrule9
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
 	}
 
 yystate46:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
+		goto yyrule29
 	case c == 'l':
 		goto yystate47
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
 yystate47:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule17
-	case c == 'e':
+		goto yyrule29
+	case c == 'o':
 		goto yystate48
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
-		goto yystate18
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
+		goto yystate28
 	}
 
 yystate48:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'b':
+		goto yystate49
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c == 'a' || c >= 'c' && c <= 'z':
+		goto yystate28
+	}
+
+yystate49:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'a':
+		goto yystate50
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
+		goto yystate28
+	}
+
+yystate50:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'l':
+		goto yystate51
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
+		goto yystate28
+	}
+
+yystate51:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule10
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate52:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'f':
+		goto yystate53
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'e' || c >= 'g' && c <= 'z':
+		goto yystate28
+	}
+
+yystate53:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule15
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate54:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'e':
+		goto yystate55
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate55:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'n':
+		goto yystate56
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
+		goto yystate28
+	}
+
+yystate56:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule12
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate57:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'r':
+		goto yystate58
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
+		goto yystate28
+	}
+
+yystate58:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'o':
+		goto yystate59
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
+		goto yystate28
+	}
+
+yystate59:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'g':
+		goto yystate60
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'f' || c >= 'h' && c <= 'z':
+		goto yystate28
+	}
+
+yystate60:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'r':
+		goto yystate61
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
+		goto yystate28
+	}
+
+yystate61:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'a':
+		goto yystate62
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
+		goto yystate28
+	}
+
+yystate62:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'm':
+		goto yystate63
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'l' || c >= 'n' && c <= 'z':
+		goto yystate28
+	}
+
+yystate63:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule6
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate64:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'r':
+		goto yystate65
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
+		goto yystate28
+	}
+
+yystate65:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'u':
+		goto yystate66
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 't' || c >= 'v' && c <= 'z':
+		goto yystate28
+	}
+
+yystate66:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'e':
+		goto yystate67
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate67:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule13
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate68:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'a':
+		goto yystate69
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
+		goto yystate28
+	}
+
+yystate69:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'r':
+		goto yystate70
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
+		goto yystate28
+	}
+
+yystate70:
 	c = y.getc()
 	switch {
 	default:
 		goto yyrule7
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
+	}
+
+yystate71:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'h':
+		goto yystate72
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'g' || c >= 'i' && c <= 'z':
+		goto yystate28
+	}
+
+yystate72:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'i':
+		goto yystate73
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'h' || c >= 'j' && c <= 'z':
+		goto yystate28
+	}
+
+yystate73:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'l':
+		goto yystate74
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
+		goto yystate28
+	}
+
+yystate74:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule29
+	case c == 'e':
+		goto yystate75
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate75:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule11
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
 	}
 
+yystate76:
+	c = y.getc()
+	switch {
+	default:
+		goto yyabort
+	case c == '|':
+		goto yystate77
+	}
+
+yystate77:
+	c = y.getc()
+	goto yyrule24
+
 yyrule1: // [ \t]+
 
 	goto yystate0
@@ -516,79 +812,145 @@ yyrule2: // [\n\r]
 		y.line++
 		goto yystate0
 	}
-yyrule3: // "program"
+yyrule3: // {comment}
+	{
+		y.comment(string(y.buf))
+		goto yystate0
+	}
+yyrule4: // {blockcomment}
+	{
+		y.comment(string(y.buf))
+		goto yystate0
+	}
+yyrule5: // {unterminated}
+	{
+		y.unterminated(string(y.buf))
+		goto yystate0
+	}
+yyrule6: // "program"
 	{
 		return PROGRAM
 	}
-yyrule4: // "var"
+yyrule7: // "var"
 	{
 		return VAR
 	}
-yyrule5: // "const"
+yyrule8: // "const"
 	{
 		return CONST
 	}
-yyrule6: // "func"
+yyrule9: // "func"
 	{
 		return FUNC
 	}
-yyrule7: // "while"
+yyrule10: // "global"
+	{
+		return GLOBAL
+	}
+yyrule11: // "while"
 	{
 		return WHILE
 	}
-yyrule8: // "if"
+yyrule12: // "len"
+	{
+		return LEN
+	}
+yyrule13: // "true"
+	{
+		return TRUE
+	}
+yyrule14: // "false"
+	{
+		return FALSE
+	}
+yyrule15: // "if"
 	{
 		return IF
 	}
-yyrule9: // "else"
+yyrule16: // "else"
 	{
 		return ELSE
 	}
-yyrule10: // "<"
+yyrule17: // "<"
 	{
 		return LT
 	}
-yyrule11: // ">"
+yyrule18: // ">"
 	{
 		return GT
 	}
-yyrule12: // "<="
+yyrule19: // "<="
 	{
 		return LE
 	}
-yyrule13: // ">="
+yyrule20: // ">="
 	{
 		return GE
 	}
-yyrule14: // "!="
+yyrule21: // "!="
 	{
 		return NE
 	}
-yyrule15: // "=="
+yyrule22: // "=="
 	{
 		return EQ
 	}
-yyrule16: // "="
+yyrule23: // "&&"
+	{
+		return AND
+	}
+yyrule24: // "||"
+	{
+		return OR
+	}
+yyrule25: // "!"
+	{
+		return NOT
+	}
+yyrule26: // "="
 	{
 		return ASSIGN
 	}
-yyrule17: // {identifier}
+yyrule27: // "."
+	{
+		return '.'
+	}
+yyrule28: // "/"
+	{
+		return '/'
+	}
+yyrule29: // {identifier}
 	{
 		return y.identifier(val, string(y.buf))
 	}
-yyrule18: // {integer}
+yyrule30: // {integer}
 	{
 		return y.integer(val, string(y.buf))
 	}
-yyrule19: // {number}
+yyrule31: // {number}
 	{
 		return y.number(val, string(y.buf))
 	}
+yyrule32: // {string}
+	if true { // avoid go vet determining the below panic will not be reached
+		return y.string(val, string(y.buf))
+	}
 	panic("unreachable")
 
-	goto yyabort // silence unused label error
-
 yyabort: // no lexem recognized
+	// silence unused label errors for build and satisfy go vet reachability analysis
+	{
+		if false {
+			goto yyabort
+		}
+		if false {
+			goto yystate0
+		}
+		if false {
+			goto yystate1
+		}
+	}
+
 	y.empty = true
 	return int(c)
 }
@@ -1,9 +1,13 @@
+// Code generated by goyacc -o lang.go.u lang.y. DO NOT EDIT.
+
 //line lang.y:2
+
 package sml
 
 import __yyfmt__ "fmt"
 
 //line lang.y:3
+
 import (
 	"github.com/marcopeereboom/gck/ast"
 	"math/big"
@@ -17,7 +21,9 @@ type yySymType struct {
 	integer    int
 	number     *big.Rat
 	identifier string
+	str        string
 	node       ast.Node
+	pos        *ast.NodeDebugInformation // location of a token
 }
 
 const INTEGER = 57346
@@ -30,15 +36,25 @@ const IF = 57352
 const ELSE = 57353
 const EOL = 57354
 const ASSIGN = 57355
-const LE = 57356
-const GE = 57357
-const NE = 57358
-const EQ = 57359
-const LT = 57360
-const GT = 57361
-const UMINUS = 57362
-
-var yyToknames = []string{
+const STRING = 57356
+const LEN = 57357
+const TRUE = 57358
+const FALSE = 57359
+const AND = 57360
+const OR = 57361
+const NOT = 57362
+const LE = 57363
+const GE = 57364
+const NE = 57365
+const EQ = 57366
+const LT = 57367
+const GT = 57368
+const UMINUS = 57369
+
+var yyToknames = [...]string{
+	"$end",
+	"error",
+	"$unk",
 	"INTEGER",
 	"IDENTIFIER",
 	"VAR",
@@ -49,6 +65,13 @@ var yyToknames = []string{
 	"ELSE",
 	"EOL",
 	"ASSIGN",
+	"STRING",
+	"LEN",
+	"TRUE",
+	"FALSE",
+	"AND",
+	"OR",
+	"NOT",
 	"LE",
 	"GE",
 	"NE",
@@ -60,138 +83,196 @@ var yyToknames = []string{
 	"'*'",
 	"'/'",
 	"UMINUS",
+	"';'",
+	"'{'",
+	"'}'",
+	"'.'",
+	"'('",
+	"')'",
+	"','",
 }
-var yyStatenames = []string{}
+
+var yyStatenames = [...]string{}
 
 const yyEofCode = 1
 const yyErrCode = 2
-const yyMaxDepth = 200
+const yyInitialStackSize = 16
 
-//line lang.y:104
+//line lang.y:147
 
 //line yacctab:1
-var yyExca = []int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 2,
+	1, 1,
+	-2, 0,
 }
 
-const yyNprod = 33
 const yyPrivate = 57344
 
-var yyTokenNames []string
-var yyStates []string
-
-const yyLast = 126
-
-var yyAct = []int{
-
-	9, 8, 5, 57, 17, 42, 43, 44, 45, 40,
-	41, 20, 21, 22, 23, 16, 25, 27, 29, 29,
-	38, 22, 23, 33, 34, 35, 36, 37, 24, 39,
-	59, 17, 48, 47, 10, 12, 2, 1, 11, 15,
-	16, 6, 58, 51, 52, 53, 54, 55, 56, 3,
-	7, 13, 18, 0, 32, 4, 17, 49, 14, 0,
-	60, 61, 10, 12, 28, 0, 11, 15, 16, 20,
-	21, 22, 23, 10, 26, 0, 0, 11, 38, 13,
-	0, 31, 18, 4, 17, 0, 14, 10, 26, 0,
-	13, 11, 0, 0, 0, 46, 0, 14, 20, 21,
-	22, 23, 0, 50, 13, 20, 21, 22, 23, 0,
-	19, 30, 42, 43, 44, 45, 40, 41, 20, 21,
-	22, 23, 20, 21, 22, 23,
+const yyLast = 277
+
+var yyAct = [...]int8{
+	5, 9, 8, 3, 84, 83, 25, 37, 38, 73,
+	33, 34, 35, 36, 31, 32, 27, 28, 29, 30,
+	43, 45, 46, 47, 48, 42, 72, 40, 51, 52,
+	53, 54, 55, 56, 57, 58, 59, 60, 61, 62,
+	39, 63, 77, 65, 41, 23, 39, 41, 71, 67,
+	68, 39, 2, 25, 29, 30, 76, 70, 64, 11,
+	17, 1, 79, 12, 22, 23, 78, 18, 24, 13,
+	16, 14, 15, 6, 80, 20, 75, 49, 81, 82,
+	7, 0, 0, 19, 0, 85, 0, 4, 24, 69,
+	50, 21, 11, 17, 0, 0, 12, 22, 23, 0,
+	0, 0, 13, 16, 14, 15, 0, 0, 20, 27,
+	28, 29, 30, 0, 0, 0, 19, 0, 0, 0,
+	4, 24, 0, 10, 21, 11, 17, 0, 0, 12,
+	22, 23, 0, 0, 0, 13, 16, 14, 15, 0,
+	0, 20, 0, 0, 0, 0, 0, 0, 0, 19,
+	0, 0, 0, 4, 24, 37, 38, 21, 33, 34,
+	35, 36, 31, 32, 27, 28, 29, 30, 11, 44,
+	0, 0, 12, 0, 66, 0, 0, 0, 13, 16,
+	14, 15, 0, 0, 20, 0, 0, 0, 0, 0,
+	0, 0, 19, 0, 0, 0, 0, 0, 37, 38,
+	21, 33, 34, 35, 36, 31, 32, 27, 28, 29,
+	30, 37, 38, 24, 33, 34, 35, 36, 31, 32,
+	27, 28, 29, 30, 0, 74, 37, 38, 0, 33,
+	34, 35, 36, 31, 32, 27, 28, 29, 30, 0,
+	26, 37, 38, 0, 33, 34, 35, 36, 31, 32,
+	27, 28, 29, 30, 37, 0, 0, 33, 34, 35,
+	36, 31, 32, 27, 28, 29, 30, 33, 34, 35,
+	36, 31, 32, 27, 28, 29, 30,
 }
-var yyPact = []int{
-
-	58, -1000, 58, -1000, -1000, 85, -1000, -1000, -1000, -1000,
-	-1000, -1000, 15, 69, 69, 83, 83, 58, -1000, -1000,
-	69, 69, 69, 69, 69, -1000, -1000, 49, -22, 98,
-	83, -22, 30, -1, -1, -1000, -1000, 78, -1000, -1000,
-	69, 69, 69, 69, 69, 69, -26, -9, 19, -1000,
-	-1000, 102, 102, 102, 102, 102, 102, -1000, -1000, 5,
-	-1000, -1000,
+
+var yyPact = [...]int16{
+	121, -1000, 121, -1000, -1000, 208, -1000, -1000, -1000, -1000,
+	19, -1000, -1000, -1000, -1000, -1000, -9, 12, -1000, 164,
+	164, 164, 164, 164, 88, -1000, -1000, 164, 164, 164,
+	164, 164, 164, 164, 164, 164, 164, 164, 164, -1000,
+	164, 53, 164, -1000, 9, -1000, 137, 180, 180, 55,
+	14, 25, 25, -1000, -1000, 82, 82, 82, 82, 82,
+	82, 246, 236, -11, -27, 193, -1000, -1000, 45, -1000,
+	8, -1000, -1000, 164, -1000, -1000, 35, -1000, -32, -34,
+	223, -1000, -1000, -1000, 164, 223,
 }
-var yyPgo = []int{
 
-	0, 49, 36, 2, 64, 50, 1, 42, 0, 41,
-	37,
+var yyPgo = [...]int8{
+	0, 3, 52, 0, 80, 2, 76, 1, 73, 67,
+	66, 62, 61,
 }
-var yyR1 = []int{
 
-	0, 10, 1, 1, 1, 1, 1, 1, 2, 2,
-	8, 9, 5, 6, 7, 7, 7, 4, 4, 4,
-	4, 4, 4, 4, 3, 3, 3, 3, 3, 3,
-	3, 3, 3,
+var yyR1 = [...]int8{
+	0, 12, 1, 1, 1, 1, 1, 1, 1, 2,
+	2, 7, 7, 7, 9, 10, 10, 11, 11, 8,
+	4, 5, 6, 6, 6, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
 }
-var yyR2 = []int{
 
-	0, 1, 1, 2, 1, 1, 1, 1, 1, 2,
-	3, 4, 3, 4, 0, 2, 2, 3, 3, 3,
-	3, 3, 3, 3, 1, 1, 1, 2, 3, 3,
-	3, 3, 3,
+var yyR2 = [...]int8{
+	0, 1, 1, 2, 1, 1, 1, 1, 2, 1,
+	2, 3, 3, 4, 6, 0, 1, 1, 3, 4,
+	3, 4, 0, 2, 2, 1, 1, 1, 1, 1,
+	4, 1, 1, 2, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 2, 3,
 }
-var yyChk = []int{
-
-	-1000, -10, -2, -1, 25, -3, -9, -5, -6, -8,
-	4, 8, 5, 21, 28, 9, 10, 26, -1, 25,
-	20, 21, 22, 23, 13, -3, 5, -3, -4, -3,
-	28, -4, -2, -3, -3, -3, -3, -3, 29, -8,
-	18, 19, 14, 15, 16, 17, -4, -3, -8, 27,
-	25, -3, -3, -3, -3, -3, -3, 29, -7, 11,
-	-8, -6,
+
+var yyChk = [...]int16{
+	-1000, -12, -2, -1, 32, -3, -8, -4, -5, -7,
+	2, 4, 8, 14, 16, 17, 15, 5, -9, 28,
+	20, 36, 9, 10, 33, -1, 32, 27, 28, 29,
+	30, 25, 26, 21, 22, 23, 24, 18, 19, 32,
+	36, 35, 13, -3, 5, -3, -3, -3, -3, -2,
+	2, -3, -3, -3, -3, -3, -3, -3, -3, -3,
+	-3, -3, -3, -3, 5, -3, 37, -7, -7, 34,
+	2, 34, 37, 36, 32, -6, 11, 34, -10, -11,
+	-3, -7, -5, 37, 38, -3,
 }
-var yyDef = []int{
-
-	0, -2, 1, 8, 2, 0, 4, 5, 6, 7,
-	24, 25, 26, 0, 0, 0, 0, 0, 9, 3,
-	0, 0, 0, 0, 0, 27, 26, 0, 0, 0,
-	0, 0, 0, 28, 29, 30, 31, 0, 32, 12,
-	0, 0, 0, 0, 0, 0, 0, 0, 14, 10,
-	11, 17, 18, 19, 20, 21, 22, 23, 13, 0,
-	15, 16,
+
+var yyDef = [...]int8{
+	0, -2, -2, 9, 2, 0, 4, 5, 6, 7,
+	0, 25, 26, 27, 28, 29, 0, 31, 32, 0,
+	0, 0, 0, 0, 0, 10, 3, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 8,
+	0, 0, 0, 33, 31, 46, 0, 0, 0, 0,
+	0, 34, 35, 36, 37, 38, 39, 40, 41, 42,
+	43, 44, 45, 0, 0, 0, 47, 20, 22, 11,
+	0, 12, 30, 15, 19, 21, 0, 13, 0, 16,
+	17, 23, 24, 14, 0, 18,
 }
-var yyTok1 = []int{
 
+var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	28, 29, 22, 20, 3, 21, 3, 23, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 25,
+	36, 37, 29, 27, 38, 28, 35, 30, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 32,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 26, 3, 27,
+	3, 3, 3, 33, 3, 34,
 }
-var yyTok2 = []int{
 
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 15, 16, 17, 18, 19, 24,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25, 26, 31,
 }
-var yyTok3 = []int{
+
+var yyTok3 = [...]int8{
 	0,
 }
 
+var yyErrorMessages = [...]struct {
+	state int
+	token int
+	msg   string
+}{}
+
 //line yaccpar:1
 
 /*	parser for yacc output	*/
 
-var yyDebug = 0
+var (
+	yyDebug        = 0
+	yyErrorVerbose = false
+)
 
 type yyLexer interface {
 	Lex(lval *yySymType) int
 	Error(s string)
 }
 
+type yyParser interface {
+	Parse(yyLexer) int
+	Lookahead() int
+}
+
+type yyParserImpl struct {
+	lval  yySymType
+	stack [yyInitialStackSize]yySymType
+	char  int
+}
+
+func (p *yyParserImpl) Lookahead() int {
+	return p.char
+}
+
+func yyNewParser() yyParser {
+	return &yyParserImpl{}
+}
+
 const yyFlag = -1000
 
 func yyTokname(c int) string {
-	// 4 is TOKSTART above
-	if c >= 4 && c-4 < len(yyToknames) {
-		if yyToknames[c-4] != "" {
-			return yyToknames[c-4]
+	if c >= 1 && c-1 < len(yyToknames) {
+		if yyToknames[c-1] != "" {
+			return yyToknames[c-1]
 		}
 	}
 	return __yyfmt__.Sprintf("tok-%v", c)
@@ -206,51 +287,127 @@ func yyStatname(s int) string {
 	return __yyfmt__.Sprintf("state-%v", s)
 }
 
-func yylex1(lex yyLexer, lval *yySymType) int {
-	c := 0
-	char := lex.Lex(lval)
+func yyErrorMessage(state, lookAhead int) string {
+	const TOKSTART = 4
+
+	if !yyErrorVerbose {
+		return "syntax error"
+	}
+
+	for _, e := range yyErrorMessages {
+		if e.state == state && e.token == lookAhead {
+			return "syntax error: " + e.msg
+		}
+	}
+
+	res := "syntax error: unexpected " + yyTokname(lookAhead)
+
+	// To match Bison, suggest at most four expected tokens.
+	expected := make([]int, 0, 4)
+
+	// Look for shiftable tokens.
+	base := int(yyPact[state])
+	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+	}
+
+	if yyDef[state] == -2 {
+		i := 0
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
+			i += 2
+		}
+
+		// Look for tokens that we accept or reduce.
+		for i += 2; yyExca[i] >= 0; i += 2 {
+			tok := int(yyExca[i])
+			if tok < TOKSTART || yyExca[i+1] == 0 {
+				continue
+			}
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+
+		// If the default action is to accept or reduce, give up.
+		if yyExca[i+1] != 0 {
+			return res
+		}
+	}
+
+	for i, tok := range expected {
+		if i == 0 {
+			res += ", expecting "
+		} else {
+			res += " or "
+		}
+		res += yyTokname(tok)
+	}
+	return res
+}
+
+func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
+	token = 0
+	char = lex.Lex(lval)
 	if char <= 0 {
-		c = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		c = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			c = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		c = yyTok3[i+0]
-		if c == char {
-			c = yyTok3[i+1]
+		token = int(yyTok3[i+0])
+		if token == char {
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
-	if c == 0 {
-		c = yyTok2[1] /* unknown char */
+	if token == 0 {
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
-		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(c), uint(char))
+		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
 	}
-	return c
+	return char, token
 }
 
 func yyParse(yylex yyLexer) int {
+	return yyNewParser().Parse(yylex)
+}
+
+func (yyrcvr *yyParserImpl) Parse(yylex yyLexer) int {
 	var yyn int
-	var yylval yySymType
 	var yyVAL yySymType
-	yyS := make([]yySymType, yyMaxDepth)
+	var yyDollar []yySymType
+	_ = yyDollar // silence set and not used
+	yyS := yyrcvr.stack[:]
 
 	Nerrs := 0   /* number of errors */
 	Errflag := 0 /* error recovery flag */
 	yystate := 0
-	yychar := -1
+	yyrcvr.char = -1
+	yytoken := -1 // yyrcvr.char translated into internal numbering
+	defer func() {
+		// Make sure we report no lookahead when not parsing.
+		yystate = -1
+		yyrcvr.char = -1
+		yytoken = -1
+	}()
 	yyp := -1
 	goto yystack
 
@@ -263,7 +420,7 @@ ret1:
 yystack:
 	/* put a state and value onto the stack */
 	if yyDebug >= 4 {
-		__yyfmt__.Printf("char %v in %v\n", yyTokname(yychar), yyStatname(yystate))
+		__yyfmt__.Printf("char %v in %v\n", yyTokname(yytoken), yyStatname(yystate))
 	}
 
 	yyp++
@@ -276,21 +433,22 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
-	if yychar < 0 {
-		yychar = yylex1(yylex, &yylval)
+	if yyrcvr.char < 0 {
+		yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
 	}
-	yyn += yychar
+	yyn += yytoken
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yychar { /* valid shift */
-		yychar = -1
-		yyVAL = yylval
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
+		yyrcvr.char = -1
+		yytoken = -1
+		yyVAL = yyrcvr.lval
 		yystate = yyn
 		if Errflag > 0 {
 			Errflag--
@@ -300,27 +458,27 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
-		if yychar < 0 {
-			yychar = yylex1(yylex, &yylval)
+		if yyrcvr.char < 0 {
+			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
 		}
 
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
-			if yyn < 0 || yyn == yychar {
+			yyn = int(yyExca[xi+0])
+			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -329,11 +487,11 @@ yydefault:
 		/* error ... attempt to resume parsing */
 		switch Errflag {
 		case 0: /* brand new error */
-			yylex.Error("syntax error")
+			yylex.Error(yyErrorMessage(yystate, yytoken))
 			Nerrs++
 			if yyDebug >= 1 {
 				__yyfmt__.Printf("%s", yyStatname(yystate))
-				__yyfmt__.Printf(" saw %s\n", yyTokname(yychar))
+				__yyfmt__.Printf(" saw %s\n", yyTokname(yytoken))
 			}
 			fallthrough
 
@@ -342,10 +500,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -361,12 +519,13 @@ yydefault:
 
 		case 3: /* no shift yet; clobber input char */
 			if yyDebug >= 2 {
-				__yyfmt__.Printf("error recovery discards %s\n", yyTokname(yychar))
+				__yyfmt__.Printf("error recovery discards %s\n", yyTokname(yytoken))
 			}
-			if yychar == yyEofCode {
+			if yytoken == yyEofCode {
 				goto ret1
 			}
-			yychar = -1
+			yyrcvr.char = -1
+			yytoken = -1
 			goto yynewstate /* try again in the same state */
 		}
 	}
@@ -380,184 +539,325 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
+	// yyp is now the index of $0. Perform the default action. Iff the
+	// reduced production is ε, $1 is possibly out of range.
+	if yyp+1 >= len(yyS) {
+		nyys := make([]yySymType, len(yyS)*2)
+		copy(nyys, yyS)
+		yyS = nyys
+	}
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
 	switch yynt {
 
 	case 1:
-		//line lang.y:46
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:59
 		{
-			d.tree = yyS[yypt-0].node
+			d.tree = yyDollar[1].node
 		}
 	case 2:
-		//line lang.y:50
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:63
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos)
+			yyVAL.node = ast.NewOperand(yyDollar[1].pos, ast.Eos)
 		}
 	case 3:
-		//line lang.y:51
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:64
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = ast.NewDiscard(yyDollar[1].node.Debug, yyDollar[1].node)
 		}
 	case 4:
-		//line lang.y:52
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:65
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 5:
-		//line lang.y:53
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:66
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 6:
-		//line lang.y:54
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:67
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 7:
-		//line lang.y:55
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:68
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 8:
-		//line lang.y:59
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:69
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewOperand(yyDollar[2].pos, ast.Eos)
 		}
 	case 9:
-		//line lang.y:60
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:73
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[1].node
 		}
 	case 10:
-		//line lang.y:64
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:74
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = ast.NewOperand(yyDollar[1].node.Debug, ast.Eos, yyDollar[1].node, yyDollar[2].node)
 		}
 	case 11:
-		//line lang.y:68
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:78
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Assign, ast.NewIdentifier(nil, yyS[yypt-3].identifier), yyS[yypt-1].node)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[3].pos
 		}
 	case 12:
-		//line lang.y:72
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:79
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.While, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(yyDollar[1].pos, ast.Eos)
+			yyVAL.pos = yyDollar[3].pos
 		}
 	case 13:
-		//line lang.y:75
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:80
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.If, yyS[yypt-2].node, yyS[yypt-1].node, yyS[yypt-0].node)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[4].pos
 		}
 	case 14:
-		//line lang.y:78
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line lang.y:84
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eos)
+			yyVAL.node = ast.NewCall(span(yyDollar[1].pos, yyDollar[3].pos), yyDollar[1].identifier+"."+yyDollar[3].identifier, ast.Nodes(yyDollar[5].node))
 		}
 	case 15:
-		//line lang.y:79
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:88
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = ast.NewOperand(nil, ast.List)
 		}
 	case 16:
-		//line lang.y:80
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:89
 		{
-			yyVAL.node = yyS[yypt-0].node
+			yyVAL.node = yyDollar[1].node
 		}
 	case 17:
-		//line lang.y:84
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:93
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Lt, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewOperand(yyDollar[1].node.Debug, ast.List, yyDollar[1].node)
 		}
 	case 18:
-		//line lang.y:85
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:94
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Gt, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.Append(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 19:
-		//line lang.y:86
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:98
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Le, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewAssign(yyDollar[1].pos, []ast.Node{ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier)}, yyDollar[3].node)
 		}
 	case 20:
-		//line lang.y:87
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:102
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Ge, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewWhile(span(yyDollar[1].pos, yyDollar[3].pos), yyDollar[2].node, yyDollar[3].node)
 		}
 	case 21:
-		//line lang.y:88
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:106
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Ne, yyS[yypt-2].node, yyS[yypt-0].node)
+			// the statement ends at the last closing brace
+			end := yyDollar[3].pos
+			if yyDollar[4].pos != nil {
+				end = yyDollar[4].pos
+			}
+			yyVAL.node = ast.NewIf(span(yyDollar[1].pos, end), yyDollar[2].node, yyDollar[3].node, yyDollar[4].node)
+			yyVAL.pos = end
 		}
 	case 22:
-		//line lang.y:89
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:117
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Eq, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.Node{}
+			yyVAL.pos = nil
 		}
 	case 23:
-		//line lang.y:90
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:118
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[2].pos
 		}
 	case 24:
-		//line lang.y:94
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:119
 		{
-			yyVAL.node = ast.NewInteger(d.d(), yyS[yypt-0].integer)
+			yyVAL.node = yyDollar[2].node
+			yyVAL.pos = yyDollar[2].pos
 		}
 	case 25:
-		//line lang.y:95
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:123
 		{
-			yyVAL.node = ast.NewNumber(d.d(), yyS[yypt-0].number)
+			yyVAL.node = ast.NewInteger(yyDollar[1].pos, yyDollar[1].integer)
 		}
 	case 26:
-		//line lang.y:96
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:124
 		{
-			yyVAL.node = ast.NewIdentifier(d.d(), yyS[yypt-0].identifier)
+			yyVAL.node = ast.NewNumber(yyDollar[1].pos, yyDollar[1].number)
 		}
 	case 27:
-		//line lang.y:97
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:125
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Uminus, yyS[yypt-0].node)
+			yyVAL.node = ast.NewString(yyDollar[1].pos, yyDollar[1].str)
 		}
 	case 28:
-		//line lang.y:98
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:126
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Add, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewBool(yyDollar[1].pos, true)
 		}
 	case 29:
-		//line lang.y:99
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:127
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Sub, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewBool(yyDollar[1].pos, false)
 		}
 	case 30:
-		//line lang.y:100
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:128
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Mul, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Len, yyDollar[3].node)
 		}
 	case 31:
-		//line lang.y:101
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:129
 		{
-			yyVAL.node = ast.NewOperand(d.d(), ast.Div, yyS[yypt-2].node, yyS[yypt-0].node)
+			yyVAL.node = ast.NewIdentifier(yyDollar[1].pos, yyDollar[1].identifier)
 		}
 	case 32:
-		//line lang.y:102
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:130
+		{
+			yyVAL.node = yyDollar[1].node
+		}
+	case 33:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:131
+		{
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Uminus, yyDollar[2].node)
+		}
+	case 34:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:132
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Add, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 35:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:133
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Sub, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 36:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:134
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Mul, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 37:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:135
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Div, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 38:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:136
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Lt, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 39:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:137
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Gt, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 40:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:138
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Le, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 41:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:139
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Ge, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 42:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:140
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Ne, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 43:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:141
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Eq, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 44:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:142
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.And, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 45:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:143
+		{
+			yyVAL.node = ast.NewBinary(yyDollar[2].pos, ast.Or, yyDollar[1].node, yyDollar[3].node)
+		}
+	case 46:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:144
+		{
+			yyVAL.node = ast.NewUnary(yyDollar[1].pos, ast.Not, yyDollar[2].node)
+		}
+	case 47:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:145
 		{
-			yyVAL.node = yyS[yypt-1].node
+			yyVAL.node = yyDollar[2].node
 		}
 	}
 	goto yystack /* stack new state and value */
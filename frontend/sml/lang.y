@@ -15,7 +15,9 @@ var d *yylexer // being set so we don't have to type assert all the time
 	integer	   int
 	number     *big.Rat
 	identifier string
+	str        string
 	node       ast.Node
+	pos        *ast.NodeDebugInformation // location of a token
 }
 
 %token	INTEGER
@@ -28,17 +30,28 @@ var d *yylexer // being set so we don't have to type assert all the time
 %token	ELSE
 %token	EOL
 %token	ASSIGN
+%token	STRING
+%token	LEN
+%token	TRUE
+%token	FALSE
+%token	AND
+%token	OR
+%token	NOT
 
 %type	<identifier>	IDENTIFIER
 %type	<integer>	INTEGER
 %type	<number>	NUMBER
-%type	<node>		statement statementlist expression boolexpression
+%type	<str>		STRING
+%type	<node>		statement statementlist expression
 %type	<node>		while if else closedstatements identifier
+%type	<node>		functioncall arguments expressionlist
 
+%left		OR
+%left		AND
 %left		LE GE NE EQ LT GT
 %left		'+' '-'
 %left		'*' '/'
-%nonassoc	UMINUS
+%nonassoc	UMINUS NOT
 
 %%
 
@@ -47,58 +60,88 @@ program:
         ;
 
 statement:
-	  ';'			{ $$ = ast.NewOperand(d.d(), ast.Eos) }
-	| expression ';'	{ $$ = $1 }
+	  ';'			{ $$ = ast.NewOperand($<pos>1, ast.Eos) }
+	| expression ';'	{ $$ = ast.NewDiscard($1.Debug, $1) }
 	| identifier		{ $$ = $1 }
 	| while			{ $$ = $1 }
 	| if			{ $$ = $1 }
 	| closedstatements	{ $$ = $1 }
+	| error ';'		{ $$ = ast.NewOperand($<pos>2, ast.Eos) }
 	;
 
 statementlist:
 	  statement			{ $$ = $1 }
-	| statementlist statement	{ $$ = ast.NewOperand(d.d(), ast.Eos, $1, $2) }
+	| statementlist statement	{ $$ = ast.NewOperand($1.Debug, ast.Eos, $1, $2) }
 	;
 
 closedstatements:
-	  '{' statementlist '}'	{ $$ = $2 }
+	  '{' statementlist '}'	{ $$ = $2; $<pos>$ = $<pos>3 }
+	| '{' error '}'		{ $$ = ast.NewOperand($<pos>1, ast.Eos); $<pos>$ = $<pos>3 }
+	| '{' statementlist error '}'	{ $$ = $2; $<pos>$ = $<pos>4 }
+	;
+
+functioncall:
+	  IDENTIFIER '.' IDENTIFIER '(' arguments ')'	{ $$ = ast.NewCall(span($<pos>1, $<pos>3), $1+"."+$3, ast.Nodes($5)) }
+	;
+
+arguments:
+					{ $$ = ast.NewOperand(nil, ast.List) }
+	| expressionlist		{ $$ = $1 }
+	;
+
+expressionlist:
+	  expression			{ $$ = ast.NewOperand($1.Debug, ast.List, $1) }
+	| expressionlist ',' expression	{ $$ = ast.Append($1, $3) }
 	;
 
 identifier:
-	  IDENTIFIER ASSIGN expression ';'	{ $$ = ast.NewOperand(d.d(), ast.Assign, ast.NewIdentifier(nil, $1), $3) }
+	  IDENTIFIER ASSIGN expression ';'	{ $$ = ast.NewAssign($<pos>1, []ast.Node{ast.NewIdentifier($<pos>1, $1)}, $3) }
 	;
 
 while:
-	  WHILE boolexpression closedstatements { $$ = ast.NewOperand(d.d(), ast.While, $2, $3) }
+	  WHILE expression closedstatements { $$ = ast.NewWhile(span($<pos>1, $<pos>3), $2, $3) }
 	;
 if:
-	  IF boolexpression closedstatements else { $$ = ast.NewOperand(d.d(), ast.If, $2, $3, $4) }
-	;
-
-else:					{ $$ = ast.NewOperand(d.d(), ast.Eos) }
-	| ELSE closedstatements		{ $$ = $2 }
-	| ELSE if			{ $$ = $2 }
+	  IF expression closedstatements else
+		{
+			// the statement ends at the last closing brace
+			end := $<pos>3
+			if $<pos>4 != nil {
+				end = $<pos>4
+			}
+			$$ = ast.NewIf(span($<pos>1, end), $2, $3, $4)
+			$<pos>$ = end
+		}
 	;
 
-boolexpression:
-	  expression LT expression	{ $$ = ast.NewOperand(d.d(), ast.Lt, $1, $3) }
-	| expression GT expression	{ $$ = ast.NewOperand(d.d(), ast.Gt, $1, $3) }
-	| expression LE expression	{ $$ = ast.NewOperand(d.d(), ast.Le, $1, $3) }
-	| expression GE expression	{ $$ = ast.NewOperand(d.d(), ast.Ge, $1, $3) }
-	| expression NE expression	{ $$ = ast.NewOperand(d.d(), ast.Ne, $1, $3) }
-	| expression EQ expression	{ $$ = ast.NewOperand(d.d(), ast.Eq, $1, $3) }
-	| '(' boolexpression ')'	{ $$ = $2 }
+else:					{ $$ = ast.Node{}; $<pos>$ = nil }
+	| ELSE closedstatements		{ $$ = $2; $<pos>$ = $<pos>2 }
+	| ELSE if			{ $$ = $2; $<pos>$ = $<pos>2 }
 	;
 
 expression:
-	  INTEGER			{ $$ = ast.NewInteger(d.d(), $1) }
-	| NUMBER			{ $$ = ast.NewNumber(d.d(), $1) }
-	| IDENTIFIER			{ $$ = ast.NewIdentifier(d.d(), $1) }
-	| '-' expression %prec UMINUS	{ $$ = ast.NewOperand(d.d(), ast.Uminus, $2) }
-	| expression '+' expression	{ $$ = ast.NewOperand(d.d(), ast.Add, $1, $3) }
-	| expression '-' expression	{ $$ = ast.NewOperand(d.d(), ast.Sub, $1, $3) }
-	| expression '*' expression	{ $$ = ast.NewOperand(d.d(), ast.Mul, $1, $3) }
-	| expression '/' expression	{ $$ = ast.NewOperand(d.d(), ast.Div, $1, $3) }
+	  INTEGER			{ $$ = ast.NewInteger($<pos>1, $1) }
+	| NUMBER			{ $$ = ast.NewNumber($<pos>1, $1) }
+	| STRING			{ $$ = ast.NewString($<pos>1, $1) }
+	| TRUE				{ $$ = ast.NewBool($<pos>1, true) }
+	| FALSE				{ $$ = ast.NewBool($<pos>1, false) }
+	| LEN '(' expression ')'	{ $$ = ast.NewUnary($<pos>1, ast.Len, $3) }
+	| IDENTIFIER			{ $$ = ast.NewIdentifier($<pos>1, $1) }
+	| functioncall			{ $$ = $1 }
+	| '-' expression %prec UMINUS	{ $$ = ast.NewUnary($<pos>1, ast.Uminus, $2) }
+	| expression '+' expression	{ $$ = ast.NewBinary($<pos>2, ast.Add, $1, $3) }
+	| expression '-' expression	{ $$ = ast.NewBinary($<pos>2, ast.Sub, $1, $3) }
+	| expression '*' expression	{ $$ = ast.NewBinary($<pos>2, ast.Mul, $1, $3) }
+	| expression '/' expression	{ $$ = ast.NewBinary($<pos>2, ast.Div, $1, $3) }
+	| expression LT expression	{ $$ = ast.NewBinary($<pos>2, ast.Lt, $1, $3) }
+	| expression GT expression	{ $$ = ast.NewBinary($<pos>2, ast.Gt, $1, $3) }
+	| expression LE expression	{ $$ = ast.NewBinary($<pos>2, ast.Le, $1, $3) }
+	| expression GE expression	{ $$ = ast.NewBinary($<pos>2, ast.Ge, $1, $3) }
+	| expression NE expression	{ $$ = ast.NewBinary($<pos>2, ast.Ne, $1, $3) }
+	| expression EQ expression	{ $$ = ast.NewBinary($<pos>2, ast.Eq, $1, $3) }
+	| expression AND expression	{ $$ = ast.NewBinary($<pos>2, ast.And, $1, $3) }
+	| expression OR expression	{ $$ = ast.NewBinary($<pos>2, ast.Or, $1, $3) }
+	| NOT expression		{ $$ = ast.NewUnary($<pos>1, ast.Not, $2) }
 	| '(' expression ')'		{ $$ = $2 }
 	;
 %%
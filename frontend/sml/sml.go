@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
 	"github.com/marcopeereboom/gck/frontend/driver"
 )
 
@@ -49,6 +50,9 @@ func (s *SimpleMathLanguage) Compile(src string) error {
 	s.lexer = newLexer(r)
 	s.lexer.lines = lines
 	result := yyParse(s.lexer)
+	if len(s.lexer.errors) != 0 {
+		return s.lexer.errors
+	}
 	if result == 0 {
 		// wrap AST to emit init and deinit code
 		prologue := ast.NewOperand(nil, ast.NeedStart)
@@ -61,7 +65,7 @@ func (s *SimpleMathLanguage) Compile(src string) error {
 		return nil
 	}
 
-	return s.lexer.lastError
+	return fmt.Errorf("parse failed")
 }
 
 // AST returns the AST representation of the compiled code.
@@ -89,19 +93,36 @@ func (s *SimpleMathLanguage) Line(l int) (string, error) {
 	return "", fmt.Errorf("Line not implemented")
 }
 
+// Comments returns the comments of the compiled code in source order.
+func (s *SimpleMathLanguage) Comments() ([]ast.Comment, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.lexer == nil {
+		return nil, fmt.Errorf("nothing compiled")
+	}
+	return s.lexer.comments, nil
+}
+
+func init() {
+	// report the unexpected token in syntax errors
+	yyErrorVerbose = true
+}
+
 // yylexer implements the lexer interface.
 type yylexer struct {
-	src       *bufio.Reader // reader to the code
-	buf       []byte        // contains currently lexed bytes
-	empty     bool          // indicate if current is valid
-	current   byte          // current byte we are lexing
-	lastError error         // last error we saw
-	line      int           // line we are parsing
-	lines     []string      // lines, used for debug etc
-	colStart  int           // column where token starts
-	colEnd    int           // column where token ends
+	src      *bufio.Reader    // reader to the code
+	buf      []byte           // contains currently lexed bytes
+	empty    bool             // indicate if current is valid
+	current  byte             // current byte we are lexing
+	errors   diagnostics.List // all errors we saw
+	line     int              // line we are parsing
+	lines    []string         // lines, used for debug etc
+	colStart int              // column where token starts
+	colEnd   int              // column where token ends
 
-	tree ast.Node // AST representation of the provided code
+	tree     ast.Node      // AST representation of the provided code
+	comments []ast.Comment // comments in source order
 }
 
 // newLexer returns a yylexer context.
@@ -121,14 +142,44 @@ func newLexer(src *bufio.Reader) *yylexer {
 	return &y
 }
 
-// d generate debug information, short name to keep yacc code readable.
-func (y *yylexer) d() *ast.NodeDebugInformation {
-	return &ast.NodeDebugInformation{
+// Lex returns the next token and records its location in the union of the
+// parser.
+func (y *yylexer) Lex(val *yySymType) int {
+	t := y.lex(val)
+	val.pos = y.token()
+	return t
+}
+
+// token returns the location of the current token.
+// The end column is one past the last byte of the token.
+func (y *yylexer) token() *ast.NodeDebugInformation {
+	d := &ast.NodeDebugInformation{
 		LineNo:   y.line,
 		ColStart: y.colStart,
 		ColEnd:   y.colEnd,
-		Line:     y.lines[y.line],
 	}
+	// single byte tokens that are not matched by a rule are returned
+	// before the lexer moves past them
+	if y.empty {
+		d.ColEnd++
+	}
+	// the lexer moves past the last line at the end of the source
+	if y.line < len(y.lines) {
+		d.Line = y.lines[y.line]
+	}
+	return d
+}
+
+// span returns the location that starts at token from and ends at token to.
+// The columns of a location that spans lines remain those of from.
+func span(from, to *ast.NodeDebugInformation) *ast.NodeDebugInformation {
+	d := *from
+	if to.LineNo == from.LineNo {
+		d.ColEnd = to.ColEnd
+	} else {
+		d.LineEnd = to.LineNo
+	}
+	return &d
 }
 
 // getc returns the next byte from the reader.
@@ -144,15 +195,48 @@ func (y *yylexer) getc() byte {
 	return y.current
 }
 
-// Error creates an error structure from a string.
+// pos returns the location of the current token.
+func (y *yylexer) pos() diagnostics.Pos {
+	return y.token().Pos()
+}
+
+// Error records an error at the current token.
+// The parser recovers from syntax errors so there may be several.
 func (y *yylexer) Error(e string) {
-	y.lastError = fmt.Errorf("line %v,%v-%v: %v", y.line, y.colStart, y.colEnd, e)
+	y.Errorf("%v", e)
 }
 
-// Error creates an error structure using standard formating rules.
+// Error records an error using standard formating rules.
 func (y *yylexer) Errorf(format string, args ...interface{}) {
-	line := fmt.Sprintf("line %v,%v-%v: ", y.line, y.colStart, y.colEnd)
-	y.lastError = fmt.Errorf(line+format, args...)
+	y.errors = append(y.errors, diagnostics.Errorf(y.pos(),
+		diagnostics.Syntax, format, args...))
+}
+
+// comment records comment s that starts at the current token.
+// Block comments may span lines so the line and column are advanced past s.
+func (y *yylexer) comment(s string) {
+	c := ast.Comment{
+		LineNo:   y.line,
+		ColStart: y.colStart,
+		Text:     s,
+	}
+	if n := strings.Count(s, "\n"); n != 0 {
+		y.line += n
+		y.colEnd = len(s) - strings.LastIndex(s, "\n")
+	}
+	c.ColEnd = y.colEnd
+	y.comments = append(y.comments, c)
+}
+
+// unterminated reports block comment s that is not closed before the end of
+// the source.
+// The error is reported at the opening /*.
+func (y *yylexer) unterminated(s string) {
+	pos := y.pos()
+	pos.ColEnd = pos.ColStart + len("/*")
+	y.errors = append(y.errors, diagnostics.Errorf(pos,
+		diagnostics.Syntax, "unterminated block comment"))
+	y.comment(s)
 }
 
 // number returns NUMBER and sets the union of the parser to the value of s.
@@ -171,6 +255,19 @@ func (y *yylexer) identifier(val *yySymType, s string) int {
 	return IDENTIFIER
 }
 
+// string returns STRING and sets the union of the parser to the unquoted value
+// of s.
+// An invalid escape sequence is reported and the string is still returned so
+// that parsing continues.
+func (y *yylexer) string(val *yySymType, s string) int {
+	var err error
+	val.str, err = strconv.Unquote(s)
+	if err != nil {
+		y.Errorf("invalid escape sequence in string %v", s)
+	}
+	return STRING
+}
+
 // integer returns INTEGER and sets the union of the parser to the value of s.
 func (y *yylexer) integer(val *yySymType, s string) int {
 	var err error
@@ -0,0 +1,229 @@
+package sml
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+// compile compiles src and returns the diagnostics.
+func compile(t *testing.T, src string) diagnostics.List {
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diagnostics.FromError(m.Compile(src), diagnostics.Unknown)
+}
+
+func TestInvalidEscape(t *testing.T) {
+	l := compile(t, "s = \"a\\q\";\n"+
+		"x = ;\n")
+	if len(l) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", l)
+	}
+	for k, line := range []int{1, 2} {
+		if l[k].Code != diagnostics.Syntax || l[k].Pos.LineNo != line {
+			t.Fatalf("invalid diagnostic %v: %v", k, l[k])
+		}
+	}
+	if l[0].Pos.ColStart != 5 {
+		t.Fatalf("invalid column %v", l[0].Pos)
+	}
+}
+
+func TestComments(t *testing.T) {
+	src := "// line\n" +
+		"x = 1; /* block ** with * stars **/ y = 2;\n" +
+		"/* multi\n" +
+		"   line */ z = ;\n"
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the error shows that lines and columns are counted past comments
+	l := diagnostics.FromError(m.Compile(src), diagnostics.Unknown)
+	if len(l) != 1 || l[0].Pos.LineNo != 4 || l[0].Pos.ColStart != 16 {
+		t.Fatalf("invalid diagnostics %v", l)
+	}
+
+	comments, err := m.Comments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ast.Comment{
+		{LineNo: 1, ColStart: 1, ColEnd: 8, Text: "// line"},
+		{LineNo: 2, ColStart: 8, ColEnd: 36,
+			Text: "/* block ** with * stars **/"},
+		{LineNo: 3, ColStart: 1, ColEnd: 11,
+			Text: "/* multi\n   line */"},
+	}
+	if !reflect.DeepEqual(comments, expected) {
+		t.Fatalf("invalid comments %+v", comments)
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := compile(t, "x = 1; /* never\n"+
+		"closed *\n")
+	if len(l) == 0 {
+		t.Fatal("expected unterminated block comment")
+	}
+	pos := diagnostics.Pos{
+		LineNo:   1,
+		ColStart: 8,
+		ColEnd:   10,
+		Line:     "x = 1; /* never\n",
+	}
+	if l[0].Code != diagnostics.Syntax || l[0].Pos != pos {
+		t.Fatalf("invalid diagnostic %v", l[0])
+	}
+}
+
+// assigned returns the names of the assigned variables in n in source order.
+func assigned(n ast.Node) []string {
+	var names []string
+	ast.Inspect(n, func(c ast.Node) bool {
+		if a, ok := c.Value.(ast.AssignStmt); ok {
+			for _, v := range a.Targets {
+				names = append(names,
+					v.Value.(ast.NodeIdentifier).Value)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+func TestRecovery(t *testing.T) {
+	src := "x = ;\n" +
+		"y = 1;\n" +
+		"if y > 0 {\n" +
+		"\tz = 2 +;\n" +
+		"\tu = 5;\n" +
+		"}\n" +
+		"w = 3 3;\n" +
+		"v = 4;\n"
+	tests := []struct {
+		name  string
+		src   string
+		pos   [][2]int // line and column of the errors
+		names []string // assigned variables
+	}{
+		{
+			name:  "statements and blocks",
+			src:   src,
+			pos:   [][2]int{{1, 5}, {4, 9}, {7, 7}},
+			names: []string{"y", "u", "v"},
+		},
+		{
+			name: "end of file",
+			src:  src + "a = 1\n",
+			pos:  [][2]int{{1, 5}, {4, 9}, {7, 7}, {10, 1}},
+		},
+	}
+	for _, v := range tests {
+		m, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := diagnostics.FromError(m.Compile(v.src), diagnostics.Unknown)
+		if len(l) != len(v.pos) {
+			t.Fatalf("%v: expected %v diagnostics, got %v", v.name,
+				len(v.pos), l)
+		}
+		for k, d := range l {
+			if d.Code != diagnostics.Syntax ||
+				d.Pos.LineNo != v.pos[k][0] ||
+				d.Pos.ColStart != v.pos[k][1] {
+				t.Fatalf("%v: invalid diagnostic %v: %v", v.name, k,
+					d)
+			}
+		}
+		if v.names == nil {
+			continue
+		}
+
+		// parsing resumed after every error
+		a, err := m.AST()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := assigned(a); !reflect.DeepEqual(names, v.names) {
+			t.Fatalf("%v: invalid assignments %v", v.name, names)
+		}
+	}
+}
+
+// positions returns the location of the identifiers, calls, operators and
+// while statements in n by name.
+func positions(n ast.Node) map[string]ast.NodeDebugInformation {
+	p := make(map[string]ast.NodeDebugInformation)
+	ast.Inspect(n, func(c ast.Node) bool {
+		name := ""
+		switch v := c.Value.(type) {
+		case ast.NodeIdentifier:
+			name = v.Value
+		case ast.CallExpr:
+			name = v.Name + "()"
+		case ast.BinaryExpr:
+			name = "binary"
+		case ast.WhileNode:
+			name = "while"
+		}
+		if _, ok := p[name]; name != "" && !ok && c.Debug != nil {
+			d := *c.Debug
+			d.Line = ""
+			p[name] = d
+		}
+		return true
+	})
+	return p
+}
+
+func TestPositions(t *testing.T) {
+	src := "y = q;\n" +
+		"os.nope(1);\n" +
+		"z = os.number(1) <= 2;\n" +
+		"while z {\n" +
+		"\tz = false;\n" +
+		"}\n"
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := m.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nodes are located at their own token and not at the lookahead
+	expected := map[string]ast.NodeDebugInformation{
+		"y":           {LineNo: 1, ColStart: 1, ColEnd: 2},
+		"q":           {LineNo: 1, ColStart: 5, ColEnd: 6},
+		"os.nope()":   {LineNo: 2, ColStart: 1, ColEnd: 8},
+		"z":           {LineNo: 3, ColStart: 1, ColEnd: 2},
+		"os.number()": {LineNo: 3, ColStart: 5, ColEnd: 14},
+		"binary":      {LineNo: 3, ColStart: 18, ColEnd: 20},
+		"while":       {LineNo: 4, ColStart: 1, ColEnd: 6, LineEnd: 6},
+	}
+	if p := positions(a); !reflect.DeepEqual(p, expected) {
+		t.Fatalf("invalid positions %+v", p)
+	}
+
+	// single byte tokens end one past their column as well
+	l := compile(t, "x = ;\n"+
+		"y = 1 <=;\n")
+	for k, pos := range [][3]int{{1, 5, 6}, {2, 9, 10}} {
+		if len(l) <= k || l[k].Pos.LineNo != pos[0] ||
+			l[k].Pos.ColStart != pos[1] || l[k].Pos.ColEnd != pos[2] {
+			t.Fatalf("invalid diagnostics %v", l)
+		}
+	}
+}
@@ -1,6 +1,8 @@
+// Code generated by golex. DO NOT EDIT.
+
 package sml
 
-func (y *yylexer) Lex(val *yySymType) int {
+func (y *yylexer) lex(val *yySymType) int {
 	c := y.current
 	if y.empty {
 		c, y.empty = y.getc(), false
@@ -13,7 +15,6 @@ yystate0:
 
 	goto yystart1
 
-	goto yystate1 // silence unused label error
 yystate1:
 	c = y.getc()
 yystart1:
@@ -22,32 +23,46 @@ yystart1:
 		goto yyabort
 	case c == '!':
 		goto yystate4
-	case c == '.':
+	case c == '"':
 		goto yystate6
+	case c == '&':
+		goto yystate9
+	case c == '.':
+		goto yystate11
+	case c == '/':
+		goto yystate16
 	case c == '<':
-		goto yystate12
+		goto yystate22
 	case c == '=':
-		goto yystate14
+		goto yystate24
 	case c == '>':
-		goto yystate16
+		goto yystate26
 	case c == '\n' || c == '\r':
 		goto yystate3
 	case c == '\t' || c == ' ':
 		goto yystate2
 	case c == 'c':
-		goto yystate19
+		goto yystate29
 	case c == 'e':
-		goto yystate24
+		goto yystate34
+	case c == 'f':
+		goto yystate38
 	case c == 'i':
-		goto yystate28
+		goto yystate43
+	case c == 'l':
+		goto yystate45
+	case c == 't':
+		goto yystate48
 	case c == 'v':
-		goto yystate30
+		goto yystate52
 	case c == 'w':
-		goto yystate33
+		goto yystate55
+	case c == '|':
+		goto yystate60
 	case c >= '0' && c <= '9':
-		goto yystate11
-	case c >= 'A' && c <= 'Z' || c == 'a' || c == 'b' || c == 'd' || c >= 'f' && c <= 'h' || c >= 'j' && c <= 'u' || c >= 'x' && c <= 'z':
-		goto yystate18
+		goto yystate21
+	case c >= 'A' && c <= 'Z' || c == 'a' || c == 'b' || c == 'd' || c == 'g' || c == 'h' || c == 'j' || c == 'k' || c >= 'm' && c <= 's' || c == 'u' || c >= 'x' && c <= 'z':
+		goto yystate28
 	}
 
 yystate2:
@@ -67,44 +82,39 @@ yystate4:
 	c = y.getc()
 	switch {
 	default:
-		goto yyabort
+		goto yyrule22
 	case c == '=':
 		goto yystate5
 	}
 
 yystate5:
 	c = y.getc()
-	goto yyrule12
+	goto yyrule18
 
 yystate6:
 	c = y.getc()
 	switch {
 	default:
 		goto yyabort
-	case c >= '0' && c <= '9':
+	case c == '"':
 		goto yystate7
+	case c == '\\':
+		goto yystate8
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= '!' || c >= '#' && c <= '[' || c >= ']' && c <= 'ÿ':
+		goto yystate6
 	}
 
 yystate7:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule17
-	case c == 'E' || c == 'e':
-		goto yystate8
-	case c >= '0' && c <= '9':
-		goto yystate7
-	}
+	goto yyrule29
 
 yystate8:
 	c = y.getc()
 	switch {
 	default:
 		goto yyabort
-	case c == '+' || c == '-':
-		goto yystate9
-	case c >= '0' && c <= '9':
-		goto yystate10
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= 'ÿ':
+		goto yystate6
 	}
 
 yystate9:
@@ -112,279 +122,510 @@ yystate9:
 	switch {
 	default:
 		goto yyabort
-	case c >= '0' && c <= '9':
+	case c == '&':
 		goto yystate10
 	}
 
 yystate10:
 	c = y.getc()
-	switch {
-	default:
-		goto yyrule17
-	case c >= '0' && c <= '9':
-		goto yystate10
-	}
+	goto yyrule20
 
 yystate11:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule16
-	case c == '.':
-		goto yystate7
-	case c == 'E' || c == 'e':
-		goto yystate8
+		goto yyrule24
 	case c >= '0' && c <= '9':
-		goto yystate11
+		goto yystate12
 	}
 
 yystate12:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule8
-	case c == '=':
+		goto yyrule28
+	case c == 'E' || c == 'e':
 		goto yystate13
+	case c >= '0' && c <= '9':
+		goto yystate12
 	}
 
 yystate13:
 	c = y.getc()
-	goto yyrule10
+	switch {
+	default:
+		goto yyabort
+	case c == '+' || c == '-':
+		goto yystate14
+	case c >= '0' && c <= '9':
+		goto yystate15
+	}
 
 yystate14:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule14
-	case c == '=':
+		goto yyabort
+	case c >= '0' && c <= '9':
 		goto yystate15
 	}
 
 yystate15:
 	c = y.getc()
-	goto yyrule13
+	switch {
+	default:
+		goto yyrule28
+	case c >= '0' && c <= '9':
+		goto yystate15
+	}
 
 yystate16:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule9
-	case c == '=':
+		goto yyrule25
+	case c == '*':
 		goto yystate17
+	case c == '/':
+		goto yystate20
 	}
 
 yystate17:
 	c = y.getc()
-	goto yyrule11
+	switch {
+	default:
+		goto yyrule5
+	case c == '*':
+		goto yystate18
+	case c >= '\x01' && c <= ')' || c >= '+' && c <= 'ÿ':
+		goto yystate17
+	}
 
 yystate18:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
-	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yyrule5
+	case c == '*':
 		goto yystate18
+	case c == '/':
+		goto yystate19
+	case c >= '\x01' && c <= ')' || c >= '+' && c <= '.' || c >= '0' && c <= 'ÿ':
+		goto yystate17
 	}
 
 yystate19:
+	c = y.getc()
+	goto yyrule4
+
+yystate20:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule3
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= 'ÿ':
+		goto yystate20
+	}
+
+yystate21:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule27
+	case c == '.':
+		goto yystate12
+	case c == 'E' || c == 'e':
+		goto yystate13
+	case c >= '0' && c <= '9':
+		goto yystate21
+	}
+
+yystate22:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule14
+	case c == '=':
+		goto yystate23
+	}
+
+yystate23:
+	c = y.getc()
+	goto yyrule16
+
+yystate24:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule23
+	case c == '=':
+		goto yystate25
+	}
+
+yystate25:
+	c = y.getc()
+	goto yyrule19
+
+yystate26:
 	c = y.getc()
 	switch {
 	default:
 		goto yyrule15
+	case c == '=':
+		goto yystate27
+	}
+
+yystate27:
+	c = y.getc()
+	goto yyrule17
+
+yystate28:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate29:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
 	case c == 'o':
-		goto yystate20
+		goto yystate30
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'n' || c >= 'p' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate20:
+yystate30:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'n':
-		goto yystate21
+		goto yystate31
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate21:
+yystate31:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 's':
-		goto yystate22
+		goto yystate32
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate22:
+yystate32:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 't':
-		goto yystate23
+		goto yystate33
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 's' || c >= 'u' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate23:
+yystate33:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule4
+		goto yyrule7
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate24:
+yystate34:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'l':
-		goto yystate25
+		goto yystate35
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate25:
+yystate35:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 's':
-		goto yystate26
+		goto yystate36
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate26:
+yystate36:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'e':
-		goto yystate27
+		goto yystate37
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate27:
+yystate37:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule7
+		goto yyrule13
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate28:
+yystate38:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
+	case c == 'a':
+		goto yystate39
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
+		goto yystate28
+	}
+
+yystate39:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'l':
+		goto yystate40
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
+		goto yystate28
+	}
+
+yystate40:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 's':
+		goto yystate41
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'r' || c >= 't' && c <= 'z':
+		goto yystate28
+	}
+
+yystate41:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'e':
+		goto yystate42
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate42:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule11
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate43:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
 	case c == 'f':
-		goto yystate29
+		goto yystate44
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'e' || c >= 'g' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate29:
+yystate44:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule6
+		goto yyrule12
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate30:
+yystate45:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
+	case c == 'e':
+		goto yystate46
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate46:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'n':
+		goto yystate47
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'm' || c >= 'o' && c <= 'z':
+		goto yystate28
+	}
+
+yystate47:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule9
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate48:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'r':
+		goto yystate49
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
+		goto yystate28
+	}
+
+yystate49:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'u':
+		goto yystate50
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 't' || c >= 'v' && c <= 'z':
+		goto yystate28
+	}
+
+yystate50:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
+	case c == 'e':
+		goto yystate51
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
+		goto yystate28
+	}
+
+yystate51:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule10
+	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
+		goto yystate28
+	}
+
+yystate52:
+	c = y.getc()
+	switch {
+	default:
+		goto yyrule26
 	case c == 'a':
-		goto yystate31
+		goto yystate53
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'b' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate31:
+yystate53:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'r':
-		goto yystate32
+		goto yystate54
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'q' || c >= 's' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate32:
+yystate54:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule3
+		goto yyrule6
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate33:
+yystate55:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'h':
-		goto yystate34
+		goto yystate56
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'g' || c >= 'i' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate34:
+yystate56:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'i':
-		goto yystate35
+		goto yystate57
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'h' || c >= 'j' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate35:
+yystate57:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'l':
-		goto yystate36
+		goto yystate58
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'k' || c >= 'm' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate36:
+yystate58:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule15
+		goto yyrule26
 	case c == 'e':
-		goto yystate37
+		goto yystate59
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'd' || c >= 'f' && c <= 'z':
-		goto yystate18
+		goto yystate28
 	}
 
-yystate37:
+yystate59:
 	c = y.getc()
 	switch {
 	default:
-		goto yyrule5
+		goto yyrule8
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c == '_' || c >= 'a' && c <= 'z':
-		goto yystate18
+		goto yystate28
+	}
+
+yystate60:
+	c = y.getc()
+	switch {
+	default:
+		goto yyabort
+	case c == '|':
+		goto yystate61
 	}
 
+yystate61:
+	c = y.getc()
+	goto yyrule21
+
 yyrule1: // [ \t]+
 
 	goto yystate0
@@ -395,71 +636,133 @@ yyrule2: // [\n\r]
 		y.line++
 		goto yystate0
 	}
-yyrule3: // "var"
+yyrule3: // {comment}
+	{
+		y.comment(string(y.buf))
+		goto yystate0
+	}
+yyrule4: // {blockcomment}
+	{
+		y.comment(string(y.buf))
+		goto yystate0
+	}
+yyrule5: // {unterminated}
+	{
+		y.unterminated(string(y.buf))
+		goto yystate0
+	}
+yyrule6: // "var"
 	{
 		return VAR
 	}
-yyrule4: // "const"
+yyrule7: // "const"
 	{
 		return CONST
 	}
-yyrule5: // "while"
+yyrule8: // "while"
 	{
 		return WHILE
 	}
-yyrule6: // "if"
+yyrule9: // "len"
+	{
+		return LEN
+	}
+yyrule10: // "true"
+	{
+		return TRUE
+	}
+yyrule11: // "false"
+	{
+		return FALSE
+	}
+yyrule12: // "if"
 	{
 		return IF
 	}
-yyrule7: // "else"
+yyrule13: // "else"
 	{
 		return ELSE
 	}
-yyrule8: // "<"
+yyrule14: // "<"
 	{
 		return LT
 	}
-yyrule9: // ">"
+yyrule15: // ">"
 	{
 		return GT
 	}
-yyrule10: // "<="
+yyrule16: // "<="
 	{
 		return LE
 	}
-yyrule11: // ">="
+yyrule17: // ">="
 	{
 		return GE
 	}
-yyrule12: // "!="
+yyrule18: // "!="
 	{
 		return NE
 	}
-yyrule13: // "=="
+yyrule19: // "=="
 	{
 		return EQ
 	}
-yyrule14: // "="
+yyrule20: // "&&"
+	{
+		return AND
+	}
+yyrule21: // "||"
+	{
+		return OR
+	}
+yyrule22: // "!"
+	{
+		return NOT
+	}
+yyrule23: // "="
 	{
 		return ASSIGN
 	}
-yyrule15: // {identifier}
+yyrule24: // "."
+	{
+		return '.'
+	}
+yyrule25: // "/"
+	{
+		return '/'
+	}
+yyrule26: // {identifier}
 	{
 		return y.identifier(val, string(y.buf))
 	}
-yyrule16: // {integer}
+yyrule27: // {integer}
 	{
 		return y.integer(val, string(y.buf))
 	}
-yyrule17: // {number}
+yyrule28: // {number}
 	{
 		return y.number(val, string(y.buf))
 	}
+yyrule29: // {string}
+	if true { // avoid go vet determining the below panic will not be reached
+		return y.string(val, string(y.buf))
+	}
 	panic("unreachable")
 
-	goto yyabort // silence unused label error
-
 yyabort: // no lexem recognized
+	// silence unused label errors for build and satisfy go vet reachability analysis
+	{
+		if false {
+			goto yyabort
+		}
+		if false {
+			goto yystate0
+		}
+		if false {
+			goto yystate1
+		}
+	}
+
 	y.empty = true
 	return int(c)
 }
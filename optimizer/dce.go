@@ -0,0 +1,202 @@
+package optimizer
+
+import "github.com/marcopeereboom/gck/ast"
+
+// dcePass is the dead code elimination Pass.
+// It removes if branches with a constant condition that are never taken and
+// while loops with a constant false condition.
+// Statements that follow a statement that never completes, e.g. an endless
+// while true loop, are removed as well.
+// Finally functions that can not be reached from main are pruned.
+// Global declarations in removed code are retained since they change the
+// scope of variables in live code.
+type dcePass struct{}
+
+// Name implements the Pass interface.
+func (dcePass) Name() string {
+	return "dce"
+}
+
+// Run implements the Pass interface.
+// Every removed statement, branch or function counts as a change.
+func (dcePass) Run(n ast.Node) (ast.Node, int, error) {
+	var changes int
+	n = dce(n, &changes)
+	n = pruneFunctions(n, &changes)
+	return n, changes, nil
+}
+
+// constBool returns the value of n if it is a boolean constant.
+func constBool(n ast.Node) (bool, bool) {
+	b, ok := n.Value.(ast.NodeBool)
+	return b.Value, ok
+}
+
+// eos returns an empty statement that contains the global declarations of
+// the nodes that are being removed.
+func eos(d *ast.NodeDebugInformation, removed ...ast.Node) ast.Node {
+	var g []ast.Node
+	for _, v := range removed {
+		g = append(g, globals(v)...)
+	}
+	return ast.NewOperand(d, ast.Eos, g...)
+}
+
+// globals returns all global declarations in n.
+func globals(n ast.Node) []ast.Node {
+	var g []ast.Node
+	ast.Inspect(n, func(c ast.Node) bool {
+		if _, ok := c.Value.(ast.GlobalDecl); ok {
+			g = append(g, c)
+			return false
+		}
+		return true
+	})
+	return g
+}
+
+// neverCompletes returns true if execution can not continue past statement n.
+// Since the languages have no break statement a while loop with a constant
+// true condition never completes.
+func neverCompletes(n ast.Node) bool {
+	switch node := n.Value.(type) {
+	case ast.WhileNode:
+		b, ok := constBool(node.Cond)
+		return ok && b
+	case ast.NodeOperand:
+		if node.Operand != ast.Eos {
+			break
+		}
+		for _, v := range node.Nodes {
+			if neverCompletes(v) {
+				return true
+			}
+		}
+	case ast.IfNode:
+		return node.Else.Value != nil && neverCompletes(node.Then) &&
+			neverCompletes(node.Else)
+	}
+	return false
+}
+
+// dce returns a copy of n without dead statements and branches.
+func dce(n ast.Node, changes *int) ast.Node {
+	return ast.Rewrite(n, func(r ast.Node) ast.Node {
+		switch o := r.Value.(type) {
+		case ast.NodeOperand:
+			if o.Operand != ast.Eos {
+				break
+			}
+
+			// drop unreachable statements, the position of global
+			// declarations does not matter so move them up front
+			for k := 0; k < len(o.Nodes)-1; k++ {
+				if !neverCompletes(o.Nodes[k]) {
+					continue
+				}
+				*changes += len(o.Nodes) - k - 1
+				var g []ast.Node
+				for _, v := range o.Nodes[k+1:] {
+					g = append(g, globals(v)...)
+				}
+				return ast.NewOperand(r.Debug, ast.Eos,
+					append(g, o.Nodes[:k+1]...)...)
+			}
+
+		case ast.IfNode:
+			b, ok := constBool(o.Cond)
+			if !ok {
+				break
+			}
+			*changes++
+			if b {
+				if o.Else.Value != nil {
+					return ast.NewOperand(r.Debug, ast.Eos,
+						o.Then, eos(r.Debug, o.Else))
+				}
+				return o.Then
+			}
+			if o.Else.Value != nil {
+				return ast.NewOperand(r.Debug, ast.Eos,
+					eos(r.Debug, o.Then), o.Else)
+			}
+			return eos(r.Debug, o.Then)
+
+		case ast.WhileNode:
+			b, ok := constBool(o.Cond)
+			if !ok || b {
+				break
+			}
+			*changes++
+			return eos(r.Debug, o.Body)
+		}
+
+		return r
+	})
+}
+
+// functions records all function nodes in n by name.
+func functions(n ast.Node, f map[string]ast.FuncDecl) {
+	ast.Inspect(n, func(c ast.Node) bool {
+		if o, ok := c.Value.(ast.FuncDecl); ok {
+			f[o.Name] = o
+			return false
+		}
+		return true
+	})
+}
+
+// calls records the names of all functions that are called in n.
+func calls(n ast.Node, c map[string]bool) {
+	ast.Inspect(n, func(v ast.Node) bool {
+		if o, ok := v.Value.(ast.CallExpr); ok {
+			c[o.Name] = true
+		}
+		return true
+	})
+}
+
+// pruneFunctions returns a copy of n without the functions that can not be
+// reached from main.
+// Programs without a main function are returned as is.
+func pruneFunctions(n ast.Node, changes *int) ast.Node {
+	f := make(map[string]ast.FuncDecl)
+	functions(n, f)
+	if _, found := f["main"]; !found {
+		return n
+	}
+
+	// walk call graph
+	reachable := map[string]bool{"main": true}
+	todo := []string{"main"}
+	for len(todo) != 0 {
+		c := make(map[string]bool)
+		calls(f[todo[0]].Body, c)
+		todo = todo[1:]
+		for k := range c {
+			if _, found := f[k]; !found || reachable[k] {
+				// externs and known functions
+				continue
+			}
+			reachable[k] = true
+			todo = append(todo, k)
+		}
+	}
+	if len(reachable) == len(f) {
+		return n
+	}
+
+	return prune(n, reachable, changes)
+}
+
+// prune replaces the functions that are not reachable with empty statements.
+func prune(n ast.Node, reachable map[string]bool, changes *int) ast.Node {
+	return ast.Rewrite(n, func(r ast.Node) ast.Node {
+		o, ok := r.Value.(ast.FuncDecl)
+		if !ok || reachable[o.Name] {
+			return r
+		}
+		*changes++
+		return ast.NewOperand(r.Debug, ast.Eos)
+	})
+}
@@ -0,0 +1,92 @@
+package optimizer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/frontend"
+)
+
+var dceSrc = `
+func unused (x) (y) {
+	y = helper(x);
+}
+
+func helper (x) (y) {
+	y = x + 1;
+}
+
+func used (x) (y) {
+	y = x * 2;
+}
+
+func main () () {
+	global a, b;
+	a = used(1);
+	if 0 == 1 {
+		b = 11;
+	} else {
+		b = 22;
+	}
+	while 1 > 2 {
+		a = 33;
+	}
+	while true {
+		a = a + 1;
+	}
+	a = 44;
+}
+`
+
+func TestDCE(t *testing.T) {
+	fe, err := frontend.New(frontend.MYRMIDON)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = fe.Compile(dceSrc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a, err := fe.AST()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	p, err := NewPipeline("fold", "dce")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ao, stats, err := p.Run(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if stats[1].Changes != 5 {
+		t.Errorf("invalid stats %v", stats)
+	}
+
+	var b bytes.Buffer
+	err = ast.DumpPseudoAsm(ao, &b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	asm := b.String()
+	for _, v := range []string{"unused:", "helper:", "push\t11",
+		"push\t33", "push\t44"} {
+		if strings.Contains(asm, v) {
+			t.Errorf("%q not eliminated", v)
+		}
+	}
+	for _, v := range []string{"used:", "main:", "push\t22"} {
+		if !strings.Contains(asm, v) {
+			t.Errorf("%q eliminated", v)
+		}
+	}
+}
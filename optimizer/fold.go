@@ -0,0 +1,148 @@
+package optimizer
+
+import (
+	"math/big"
+
+	"github.com/marcopeereboom/gck/ast"
+)
+
+// Fold returns a copy of n in which constant integer and number expressions
+// are evaluated.
+// Only operands of the same type are folded; mixed expressions and division by
+// zero are left alone so that they fail at run time exactly like they would
+// without the optimizer.
+// Integer division truncates toward zero and numbers remain exact.
+func Fold(n ast.Node) ast.Node {
+	var changes int
+	return fold(n, &changes)
+}
+
+// foldPass is the constant folding Pass.
+type foldPass struct{}
+
+// Name implements the Pass interface.
+func (foldPass) Name() string {
+	return "fold"
+}
+
+// Run implements the Pass interface.
+// Every folded expression counts as a change.
+func (foldPass) Run(n ast.Node) (ast.Node, int, error) {
+	var changes int
+	n = fold(n, &changes)
+	return n, changes, nil
+}
+
+// fold does the work for Fold and counts the folded expressions in changes.
+func fold(n ast.Node, changes *int) ast.Node {
+	return ast.Rewrite(n, func(r ast.Node) ast.Node {
+		f, ok := foldNode(r)
+		if !ok {
+			return r
+		}
+		*changes++
+		return f
+	})
+}
+
+// foldNode evaluates n if it is an expression with constant operands.
+// It returns false if n can not be evaluated at compile time.
+func foldNode(n ast.Node) (ast.Node, bool) {
+	switch o := n.Value.(type) {
+	case ast.UnaryExpr:
+		if o.Op != ast.Uminus {
+			break
+		}
+		switch v := o.X.Value.(type) {
+		case ast.NodeInteger:
+			return ast.NewInteger(n.Debug, -v.Value), true
+		case ast.NodeNumber:
+			return ast.NewNumber(n.Debug, new(big.Rat).Neg(v.Value)),
+				true
+		}
+
+	case ast.BinaryExpr:
+		switch v0 := o.X.Value.(type) {
+		case ast.NodeInteger:
+			v1, ok := o.Y.Value.(ast.NodeInteger)
+			if ok {
+				return foldInteger(n.Debug, o.Op, v0.Value,
+					v1.Value)
+			}
+		case ast.NodeNumber:
+			v1, ok := o.Y.Value.(ast.NodeNumber)
+			if ok {
+				return foldNumber(n.Debug, o.Op, v0.Value,
+					v1.Value)
+			}
+		}
+	}
+
+	return n, false
+}
+
+// foldInteger evaluates x op y.
+// It returns false if the expression can not be evaluated at compile time.
+func foldInteger(d *ast.NodeDebugInformation, op int, x, y int) (ast.Node,
+	bool) {
+
+	switch op {
+	case ast.Add:
+		return ast.NewInteger(d, x+y), true
+	case ast.Sub:
+		return ast.NewInteger(d, x-y), true
+	case ast.Mul:
+		return ast.NewInteger(d, x*y), true
+	case ast.Div:
+		if y == 0 {
+			return ast.Node{}, false
+		}
+		return ast.NewInteger(d, x/y), true
+	case ast.Lt:
+		return ast.NewBool(d, x < y), true
+	case ast.Gt:
+		return ast.NewBool(d, x > y), true
+	case ast.Le:
+		return ast.NewBool(d, x <= y), true
+	case ast.Ge:
+		return ast.NewBool(d, x >= y), true
+	case ast.Ne:
+		return ast.NewBool(d, x != y), true
+	case ast.Eq:
+		return ast.NewBool(d, x == y), true
+	}
+	return ast.Node{}, false
+}
+
+// foldNumber evaluates x op y.
+// It returns false if the expression can not be evaluated at compile time.
+func foldNumber(d *ast.NodeDebugInformation, op int, x, y *big.Rat) (ast.Node,
+	bool) {
+
+	switch op {
+	case ast.Add:
+		return ast.NewNumber(d, new(big.Rat).Add(x, y)), true
+	case ast.Sub:
+		return ast.NewNumber(d, new(big.Rat).Sub(x, y)), true
+	case ast.Mul:
+		return ast.NewNumber(d, new(big.Rat).Mul(x, y)), true
+	case ast.Div:
+		if y.Sign() == 0 {
+			return ast.Node{}, false
+		}
+		return ast.NewNumber(d, new(big.Rat).Quo(x, y)), true
+	case ast.Lt:
+		return ast.NewBool(d, x.Cmp(y) < 0), true
+	case ast.Gt:
+		return ast.NewBool(d, x.Cmp(y) > 0), true
+	case ast.Le:
+		return ast.NewBool(d, x.Cmp(y) <= 0), true
+	case ast.Ge:
+		return ast.NewBool(d, x.Cmp(y) >= 0), true
+	case ast.Ne:
+		return ast.NewBool(d, x.Cmp(y) != 0), true
+	case ast.Eq:
+		return ast.NewBool(d, x.Cmp(y) == 0), true
+	}
+	return ast.Node{}, false
+}
@@ -0,0 +1,80 @@
+package optimizer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+)
+
+func i(v int) ast.Node {
+	return ast.NewInteger(nil, v)
+}
+
+func r(a, b int64) ast.Node {
+	return ast.NewNumber(nil, big.NewRat(a, b))
+}
+
+func op(o int, args ...ast.Node) ast.Node {
+	if len(args) == 1 {
+		return ast.NewUnary(nil, o, args[0])
+	}
+	return ast.NewBinary(nil, o, args[0], args[1])
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		n    ast.Node
+		want interface{}
+	}{
+		// 12 + 13 * (14 + 15)
+		{op(ast.Add, i(12), op(ast.Mul, i(13), op(ast.Add, i(14), i(15)))),
+			389},
+		{op(ast.Div, i(7), i(2)), 3},
+		{op(ast.Div, op(ast.Uminus, i(7)), i(2)), -3},
+		{op(ast.Add, r(1, 3), r(1, 3)), big.NewRat(2, 3)},
+		{op(ast.Div, r(1, 1), r(3, 1)), big.NewRat(1, 3)},
+		{op(ast.Lt, i(1), i(2)), true},
+		{op(ast.Eq, r(1, 2), r(2, 4)), true},
+	}
+
+	for k, v := range tests {
+		orig := ast.Clone(v.n)
+		f := Fold(v.n)
+		if !ast.Equal(orig, v.n) {
+			t.Errorf("%v: original modified", k)
+		}
+		switch want := v.want.(type) {
+		case int:
+			got, ok := f.Value.(ast.NodeInteger)
+			if !ok || got.Value != want {
+				t.Errorf("%v: got %v want %v", k, f.Value, want)
+			}
+		case *big.Rat:
+			got, ok := f.Value.(ast.NodeNumber)
+			if !ok || got.Value.Cmp(want) != 0 {
+				t.Errorf("%v: got %v want %v", k, f.Value, want)
+			}
+		case bool:
+			got, ok := f.Value.(ast.NodeBool)
+			if !ok || got.Value != want {
+				t.Errorf("%v: got %v want %v", k, f.Value, want)
+			}
+		}
+	}
+}
+
+func TestFoldNot(t *testing.T) {
+	tests := []ast.Node{
+		op(ast.Div, i(1), i(0)),    // run time error
+		op(ast.Add, i(1), r(1, 2)), // mixed types
+		op(ast.Add, i(1), ast.NewIdentifier(nil, "x")),
+	}
+
+	for k, v := range tests {
+		f := Fold(v)
+		if _, ok := f.Value.(ast.BinaryExpr); !ok {
+			t.Errorf("%v: folded into %v", k, f.Value)
+		}
+	}
+}
@@ -1,11 +1,24 @@
 // optimizer walks an AST tree and tries to do optimizations on it.
+//
+// Optimizations are implemented as passes that are registered by name.
+// A Pipeline runs a selection of passes until the AST no longer changes.
+// Optimization levels map to a predefined selection of passes.
 package optimizer
 
 import "github.com/marcopeereboom/gck/ast"
 
 // Optimize transforms n AST and returns an optimized version of it.
-// Currently optimizer returns a clone of the AST.
-// This is at the moment just a placeholder.
+// It runs all passes of the highest optimization level.
+// The original AST is not modified.
 func Optimize(n ast.Node) (ast.Node, error) {
-	return ast.Clone(n), nil
+	names, err := Level(len(levels) - 1)
+	if err != nil {
+		return n, err
+	}
+	p, err := NewPipeline(names...)
+	if err != nil {
+		return n, err
+	}
+	n, _, err = p.Run(n)
+	return n, err
 }
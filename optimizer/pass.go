@@ -0,0 +1,147 @@
+package optimizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcopeereboom/gck/ast"
+)
+
+// Pass is a single optimization.
+type Pass interface {
+	// Name returns the name the pass is selected by, e.g. fold.
+	Name() string
+
+	// Run returns a transformed copy of n and the number of changes
+	// that were made.
+	// n must not be modified.
+	Run(n ast.Node) (ast.Node, int, error)
+}
+
+// Stats contains what a pass did during a pipeline run.
+type Stats struct {
+	Name    string // pass name
+	Runs    int    // number of times the pass was run
+	Changes int    // total number of changes made
+}
+
+// String returns human readable statistics.
+func (s Stats) String() string {
+	return fmt.Sprintf("%-8v runs %v changes %v", s.Name, s.Runs, s.Changes)
+}
+
+const (
+	// maxIterations bounds the number of times a pipeline runs all its
+	// passes while looking for a fixed point.
+	maxIterations = 16
+)
+
+var (
+	passes = make(map[string]Pass) // all registered passes by name
+
+	// levels maps an optimization level to the passes it runs, in order.
+	levels = [][]string{
+		0: nil,
+		1: {"fold"},
+		2: {"fold", "dce"},
+	}
+)
+
+func init() {
+	// builtin passes
+	for _, v := range []Pass{foldPass{}, dcePass{}} {
+		passes[v.Name()] = v
+	}
+}
+
+// Register adds pass p.
+// Pass names must be unique.
+func Register(p Pass) error {
+	name := p.Name()
+	if name == "" || strings.Contains(name, ",") {
+		return fmt.Errorf("invalid pass name: %q", name)
+	}
+	if _, found := passes[name]; found {
+		return fmt.Errorf("pass already registered: %v", name)
+	}
+	passes[name] = p
+	return nil
+}
+
+// Passes returns the names of all registered passes sorted by name.
+func Passes() []string {
+	names := make([]string, 0, len(passes))
+	for k := range passes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Level returns the names of the passes that are run for optimization level
+// l.
+// Levels beyond the highest level run the highest level.
+func Level(l int) ([]string, error) {
+	if l < 0 {
+		return nil, fmt.Errorf("invalid optimization level: %v", l)
+	}
+	if l >= len(levels) {
+		l = len(levels) - 1
+	}
+	return levels[l], nil
+}
+
+// Pipeline runs passes in order until none of them changes the AST anymore.
+type Pipeline struct {
+	passes []Pass
+}
+
+// NewPipeline returns a pipeline that runs the passes names in the provided
+// order.
+func NewPipeline(names ...string) (*Pipeline, error) {
+	p := Pipeline{}
+	for _, v := range names {
+		pass, found := passes[v]
+		if !found {
+			return nil, fmt.Errorf("unknown pass: %v; available: %v",
+				v, strings.Join(Passes(), ","))
+		}
+		p.passes = append(p.passes, pass)
+	}
+	return &p, nil
+}
+
+// Run transforms n and returns the optimized AST and statistics for every
+// pass in pipeline order.
+// The original AST is not modified.
+func (p *Pipeline) Run(n ast.Node) (ast.Node, []Stats, error) {
+	stats := make([]Stats, len(p.passes))
+	for k, v := range p.passes {
+		stats[k].Name = v.Name()
+	}
+
+	for i := 0; i < maxIterations; i++ {
+		changes := 0
+		for k, v := range p.passes {
+			var (
+				c   int
+				err error
+			)
+			n, c, err = v.Run(n)
+			if err != nil {
+				return n, stats, fmt.Errorf("%v: %v", v.Name(), err)
+			}
+			stats[k].Runs++
+			stats[k].Changes += c
+			changes += c
+		}
+
+		// fixed point
+		if changes == 0 {
+			break
+		}
+	}
+
+	return n, stats, nil
+}
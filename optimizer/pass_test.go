@@ -0,0 +1,55 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+)
+
+func TestPipeline(t *testing.T) {
+	_, err := NewPipeline("fold", "moo")
+	if err == nil {
+		t.Errorf("expected unknown pass")
+		return
+	}
+
+	p, err := NewPipeline("fold")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	n := ast.NewAssign(nil, []ast.Node{ast.NewIdentifier(nil, "a")},
+		op(ast.Add, i(1), op(ast.Mul, i(2), i(3))))
+	n, stats, err := p.Run(n)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(stats) != 1 || stats[0].Changes != 2 || stats[0].Runs != 2 {
+		t.Errorf("invalid stats %v", stats)
+		return
+	}
+	v, ok := n.Value.(ast.AssignStmt).Value.Value.(ast.NodeInteger)
+	if !ok || v.Value != 7 {
+		t.Errorf("not folded")
+		return
+	}
+}
+
+func TestLevel(t *testing.T) {
+	l0, err := Level(0)
+	if err != nil || len(l0) != 0 {
+		t.Errorf("level 0 must not run passes")
+		return
+	}
+	l1, err := Level(1)
+	if err != nil || len(l1) == 0 {
+		t.Errorf("level 1 must run passes")
+		return
+	}
+	_, err = Level(-1)
+	if err == nil {
+		t.Errorf("expected invalid level")
+		return
+	}
+}
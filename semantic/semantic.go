@@ -0,0 +1,294 @@
+// semantic verifies that an AST is meaningful before it is handed to a
+// backend.
+//
+// The checks are the ones that the grammars can not express: variables must
+// be assigned before they are used, function results must be assigned,
+// called functions must exist, functions must be defined only once and a
+// program must have a main function.
+// All problems are reported at once as a diagnostics.List.
+package semantic
+
+import (
+	"sort"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+// set is a set of variable names.
+type set map[string]bool
+
+// copy returns a copy of s.
+func (s set) copy() set {
+	c := make(set, len(s))
+	for k := range s {
+		c[k] = true
+	}
+	return c
+}
+
+// scope describes the variables of the code that is being checked.
+// Variables that are not local are global.
+type scope struct {
+	locals   set // local variables, nil outside of functions
+	assigned set // variables that are assigned somewhere in the scope
+	reported set // variables that have been reported already
+}
+
+// checker is the semantic analysis context.
+type checker struct {
+	funcs   set // functions defined in the source
+	externs set // functions provided by the target
+	globals set // global variables that are assigned by a function
+	diags   diagnostics.List
+}
+
+// errorf records an error at the location of node n.
+func (c *checker) errorf(n ast.Node, code diagnostics.Code, format string,
+	args ...interface{}) {
+
+	c.diags = append(c.diags, diagnostics.Errorf(n.Debug.Pos(), code,
+		format, args...))
+}
+
+// assignments returns the variables that are assigned in n.
+func assignments(n ast.Node) set {
+	s := make(set)
+	ast.Inspect(n, func(c ast.Node) bool {
+		if a, ok := c.Value.(ast.AssignStmt); ok {
+			for _, v := range a.Targets {
+				s[v.Value.(ast.NodeIdentifier).Value] = true
+			}
+		}
+		return true
+	})
+	return s
+}
+
+// declaredGlobals returns the variables that are declared global in n.
+func declaredGlobals(n ast.Node) set {
+	s := make(set)
+	ast.Inspect(n, func(c ast.Node) bool {
+		if g, ok := c.Value.(ast.GlobalDecl); ok {
+			for _, v := range g.Names {
+				s[v.Value.(ast.NodeIdentifier).Value] = true
+			}
+		}
+		return true
+	})
+	return s
+}
+
+// names returns the identifier names in l.
+func names(l []ast.Node) []string {
+	s := make([]string, 0, len(l))
+	for _, v := range l {
+		s = append(s, v.Value.(ast.NodeIdentifier).Value)
+	}
+	return s
+}
+
+// newScope returns the scope of function f.
+// Parameters and results are local, as are all variables that are assigned
+// in the function and that are not declared global.
+func newScope(f ast.FuncDecl) *scope {
+	s := &scope{
+		locals:   make(set),
+		assigned: assignments(f.Body),
+		reported: make(set),
+	}
+	for _, v := range append(names(f.Params), names(f.Results)...) {
+		s.locals[v] = true
+		s.assigned[v] = true
+	}
+	globals := declaredGlobals(f.Body)
+	for k := range s.assigned {
+		if !globals[k] {
+			s.locals[k] = true
+		}
+	}
+	return s
+}
+
+// collect records the functions that are defined in n and the global
+// variables that they assign.
+func (c *checker) collect(n ast.Node) {
+	ast.Inspect(n, func(v ast.Node) bool {
+		f, ok := v.Value.(ast.FuncDecl)
+		if !ok {
+			return true
+		}
+		if c.funcs[f.Name] {
+			c.errorf(v, diagnostics.DuplicateFunction,
+				"function %v redefined", f.Name)
+		}
+		c.funcs[f.Name] = true
+
+		s := newScope(f)
+		for k := range s.assigned {
+			if !s.locals[k] {
+				c.globals[k] = true
+			}
+		}
+		return false
+	})
+}
+
+// use verifies that variable id, that is used by node n, has been assigned.
+func (c *checker) use(n ast.Node, id string, s *scope, assigned set) {
+	if assigned[id] || s.reported[id] {
+		return
+	}
+	local := s.locals == nil || s.locals[id]
+	if !local && c.globals[id] {
+		// assigned by another function
+		return
+	}
+
+	s.reported[id] = true
+	if local && s.assigned[id] {
+		c.errorf(n, diagnostics.UndefinedVariable,
+			"variable %v used before assignment", id)
+		return
+	}
+	c.errorf(n, diagnostics.UndefinedVariable, "undefined variable %v",
+		id)
+}
+
+// expression checks the variables and calls in expression n.
+func (c *checker) expression(n ast.Node, s *scope, assigned set) {
+	ast.Inspect(n, func(v ast.Node) bool {
+		switch node := v.Value.(type) {
+		case ast.NodeIdentifier:
+			c.use(v, node.Value, s, assigned)
+		case ast.CallExpr:
+			if !c.funcs[node.Name] && !c.externs[node.Name] {
+				c.errorf(v, diagnostics.UndefinedFunction,
+					"undefined function %v", node.Name)
+			}
+		}
+		return true
+	})
+}
+
+// statement checks statement n.
+// assigned contains the variables that are definitely assigned before n and
+// it is updated with the variables that are definitely assigned after n.
+func (c *checker) statement(n ast.Node, s *scope, assigned set) {
+	switch node := n.Value.(type) {
+	case ast.AssignStmt:
+		c.expression(node.Value, s, assigned)
+		for _, v := range names(node.Targets) {
+			assigned[v] = true
+		}
+	case ast.DiscardStmt:
+		c.expression(node.X, s, assigned)
+	case ast.IfNode:
+		c.expression(node.Cond, s, assigned)
+		then := assigned.copy()
+		c.statement(node.Then, s, then)
+		if node.Else.Value == nil {
+			return
+		}
+		els := assigned.copy()
+		c.statement(node.Else, s, els)
+		// only variables that are assigned by both branches
+		for k := range then {
+			if els[k] {
+				assigned[k] = true
+			}
+		}
+	case ast.WhileNode:
+		// the body may not run at all
+		c.expression(node.Cond, s, assigned)
+		c.statement(node.Body, s, assigned.copy())
+	case ast.FuncDecl:
+		fs := newScope(node)
+		fa := make(set)
+		for _, v := range names(node.Params) {
+			fa[v] = true
+		}
+		c.statement(node.Body, fs, fa)
+
+		// results must be assigned on every path through the body
+		for _, v := range node.Results {
+			id := v.Value.(ast.NodeIdentifier).Value
+			if fa[id] || fs.reported[id] {
+				continue
+			}
+			c.errorf(v, diagnostics.UndefinedVariable,
+				"result %v is not always assigned", id)
+		}
+	case ast.GlobalDecl:
+	case ast.NodeOperand:
+		for _, v := range node.Nodes {
+			c.statement(v, s, assigned)
+		}
+	default:
+		c.expression(n, s, assigned)
+	}
+}
+
+// hasMain returns true if n contains a main function or code that is wrapped
+// in a main function by the backend.
+func (c *checker) hasMain(n ast.Node) bool {
+	if c.funcs["main"] {
+		return true
+	}
+	found := false
+	ast.Inspect(n, func(v ast.Node) bool {
+		if o, ok := v.Value.(ast.NodeOperand); ok &&
+			o.Operand == ast.NeedStart {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// sorted returns diagnostics l in source order.
+// Diagnostics without a location go last.
+func sorted(l diagnostics.List) diagnostics.List {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Pos, l[j].Pos
+		if !a.IsValid() || !b.IsValid() {
+			return a.IsValid() && !b.IsValid()
+		}
+		if a.LineNo != b.LineNo {
+			return a.LineNo < b.LineNo
+		}
+		return a.ColStart < b.ColStart
+	})
+	return l
+}
+
+// Check verifies AST n.
+// Calls to functions that are not defined in n are resolved with externs.
+// It returns a diagnostics.List with all problems in source order or nil if
+// there are none.
+func Check(n ast.Node, externs ...ast.Extern) error {
+	c := checker{
+		funcs:   make(set),
+		externs: make(set),
+		globals: make(set),
+	}
+	for _, v := range externs {
+		c.externs[v.Name] = true
+	}
+
+	c.collect(n)
+	top := &scope{
+		assigned: assignments(n),
+		reported: make(set),
+	}
+	c.statement(n, top, make(set))
+	if !c.hasMain(n) {
+		c.diags = append(c.diags, diagnostics.Errorf(diagnostics.Pos{},
+			diagnostics.MissingMain, "function main not found"))
+	}
+
+	if len(c.diags) == 0 {
+		return nil
+	}
+	return sorted(c.diags)
+}
@@ -0,0 +1,117 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+	"github.com/marcopeereboom/gck/frontend"
+)
+
+var osPrint = ast.Extern{Name: "os.print"}
+
+// check compiles src in language lang and returns the diagnostic codes and
+// lines of Check.
+func check(t *testing.T, lang, src string) []diagnostics.Diagnostic {
+	fe, err := frontend.New(lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fe.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fe.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d []diagnostics.Diagnostic
+	for _, v := range diagnostics.FromError(Check(a, osPrint),
+		diagnostics.Unknown) {
+		d = append(d, diagnostics.Diagnostic{
+			Code: v.Code,
+			Pos:  diagnostics.Pos{LineNo: v.Pos.LineNo},
+		})
+	}
+	return d
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		lang  string
+		src   string
+		codes []diagnostics.Code
+		lines []int
+	}{
+		{
+			lang: frontend.SML,
+			src:  "x = 1;\nwhile x < 3 { x = x + 1; }\nos.print(x);\n",
+		},
+		{
+			lang:  frontend.SML,
+			src:   "x = y;\ny = 1;\n",
+			codes: []diagnostics.Code{diagnostics.UndefinedVariable},
+			lines: []int{1},
+		},
+		{
+			// both branches assign
+			lang: frontend.SML,
+			src: "if true { x = 1; } else { x = 2; }\n" +
+				"os.print(x);\n",
+		},
+		{
+			// the loop may not run
+			lang: frontend.SML,
+			src: "while false { x = 1; }\n" +
+				"os.print(x);\n",
+			codes: []diagnostics.Code{diagnostics.UndefinedVariable},
+			lines: []int{2},
+		},
+		{
+			lang: frontend.MYRMIDON,
+			src: "func f (a) (b) {\n\tb = a;\n}\n" +
+				"func main () () {\n\tx = f(1);\n" +
+				"\tos.print(x);\n}\n",
+		},
+		{
+			// the result is not assigned if x <= 0
+			lang: frontend.MYRMIDON,
+			src: "func f (x) (y) {\n\tif x > 0 {\n\t\ty = 1;\n" +
+				"\t}\n}\n" +
+				"func main () () {\n\tz = f(1);\n}\n",
+			codes: []diagnostics.Code{diagnostics.UndefinedVariable},
+			lines: []int{1},
+		},
+		{
+			// globals are assigned by other functions
+			lang: frontend.MYRMIDON,
+			src: "func f () () {\n\tglobal g;\n\tg = 1;\n}\n" +
+				"func main () () {\n\tf();\n\tos.print(g);\n}\n",
+		},
+		{
+			lang: frontend.MYRMIDON,
+			src: "func f () () {\n}\nfunc f () () {\n}\n" +
+				"func g () () {\n\th();\n\tos.print(u);\n}\n",
+			codes: []diagnostics.Code{
+				diagnostics.DuplicateFunction,
+				diagnostics.UndefinedFunction,
+				diagnostics.UndefinedVariable,
+				diagnostics.MissingMain,
+			},
+			lines: []int{3, 6, 7, 0},
+		},
+	}
+	for k, v := range tests {
+		d := check(t, v.lang, v.src)
+		if len(d) != len(v.codes) {
+			t.Fatalf("%v: got %v, want %v", k, d, v.codes)
+		}
+		for kk, vv := range d {
+			if vv.Code != v.codes[kk] || vv.Pos.LineNo != v.lines[kk] {
+				t.Fatalf("%v: got %v line %v, want %v line %v",
+					k, vv.Code, vv.Pos.LineNo, v.codes[kk],
+					v.lines[kk])
+			}
+		}
+	}
+}
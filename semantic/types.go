@@ -0,0 +1,406 @@
+package semantic
+
+import (
+	"math/big"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+)
+
+// NumberConversion is the extern that converts an integer to a number.
+// TypeCheck inserts calls to it where an integer is used as a number.
+const NumberConversion = "os.number"
+
+// maxPasses bounds the number of inference passes.
+// Types only ever widen so inference converges quickly.
+const maxPasses = 64
+
+// function contains the inferred types of a function.
+// Parameter and result types are the types of their local variables.
+type function struct {
+	decl   ast.FuncDecl
+	scope  *scope
+	locals map[string]string // types of local variables
+}
+
+// typer is the type inference context.
+// An empty type means that the type is not known at compile time.
+type typer struct {
+	externs map[string]ast.Extern
+	funcs   map[string]*function
+	globals map[string]string // types of global variables
+	changed bool              // a type changed during the last pass
+	final   bool              // report errors and insert conversions
+	diags   diagnostics.List
+}
+
+// numeric returns true if type t is an integer or a number.
+func numeric(t string) bool {
+	return t == ast.TypeInt || t == ast.TypeNumber
+}
+
+// name returns the name of type t for use in messages.
+func name(t string) string {
+	if t == ast.TypeInt {
+		return "integer"
+	}
+	return t
+}
+
+// widen returns the type of a variable of type t that is assigned a value of
+// type v.
+// Integers widen to numbers, other mismatches retain the original type.
+func widen(t, v string) string {
+	switch {
+	case t == "":
+		return v
+	case t == ast.TypeInt && v == ast.TypeNumber:
+		return v
+	}
+	return t
+}
+
+// errorf records an error at the location of node n during the final pass.
+func (t *typer) errorf(n ast.Node, code diagnostics.Code, format string,
+	args ...interface{}) {
+
+	if t.final {
+		t.diags = append(t.diags, diagnostics.Errorf(n.Debug.Pos(),
+			code, format, args...))
+	}
+}
+
+// vars returns the variable types that contain variable id in function f or
+// in the global scope if f is nil.
+func (t *typer) vars(f *function, id string) map[string]string {
+	if f != nil && f.scope.locals[id] {
+		return f.locals
+	}
+	return t.globals
+}
+
+// assign records that variable id of function f is assigned a value of type
+// v.
+func (t *typer) assign(f *function, id, v string) {
+	vars := t.vars(f, id)
+	if w := widen(vars[id], v); w != vars[id] {
+		vars[id] = w
+		t.changed = true
+	}
+}
+
+// convert returns n converted to type want if n has type got.
+// Only integers are converted, to numbers; the conversion of an integer
+// constant is done at compile time.
+// ok is false if the conversion is not allowed.
+func (t *typer) convert(n ast.Node, got, want string) (ast.Node, bool) {
+	if got == "" || want == "" || want == ast.TypeAny || got == want {
+		return n, true
+	}
+	if got != ast.TypeInt || want != ast.TypeNumber {
+		return n, false
+	}
+	if !t.final {
+		return n, true
+	}
+	if i, ok := n.Value.(ast.NodeInteger); ok {
+		return ast.NewNumber(n.Debug, big.NewRat(int64(i.Value), 1)),
+			true
+	}
+	if _, found := t.externs[NumberConversion]; !found {
+		return n, false
+	}
+	return ast.NewCall(n.Debug, NumberConversion, []ast.Node{n}), true
+}
+
+// binary infers the type of binary expression n and converts mixed integer
+// and number operands to numbers.
+func (t *typer) binary(n ast.Node, f *function) (ast.Node, string) {
+	node := n.Value.(ast.BinaryExpr)
+	x, xt := t.expression(node.X, f)
+	y, yt := t.expression(node.Y, f)
+	invalid := func() {
+		t.errorf(n, diagnostics.InvalidOperation,
+			"invalid operation: %v %v %v", name(xt),
+			ast.OpString(node.Op), name(yt))
+	}
+
+	result := ""
+	switch node.Op {
+	case ast.And, ast.Or:
+		result = ast.TypeBool
+		for _, v := range []string{xt, yt} {
+			if v != "" && v != ast.TypeBool {
+				invalid()
+				break
+			}
+		}
+		return ast.NewBinary(n.Debug, node.Op, x, y), result
+	case ast.Lt, ast.Gt, ast.Le, ast.Ge, ast.Ne, ast.Eq:
+		result = ast.TypeBool
+	}
+	if xt == "" || yt == "" {
+		return ast.NewBinary(n.Debug, node.Op, x, y), result
+	}
+
+	operand := xt
+	switch {
+	case numeric(xt) && numeric(yt):
+		var ok bool
+		operand = widen(xt, yt)
+		x, ok = t.convert(x, xt, operand)
+		if ok {
+			y, ok = t.convert(y, yt, operand)
+		}
+		if !ok {
+			invalid()
+		}
+	case xt != yt:
+		invalid()
+	case xt == ast.TypeString:
+		switch node.Op {
+		case ast.Sub, ast.Mul, ast.Div:
+			invalid()
+		}
+	case xt == ast.TypeBool:
+		if node.Op != ast.Eq && node.Op != ast.Ne {
+			invalid()
+		}
+	}
+	if result == "" {
+		result = operand
+	}
+	return ast.NewBinary(n.Debug, node.Op, x, y), result
+}
+
+// call infers the types of the arguments of call n and of its result.
+// Arguments are converted to the parameter types of the function.
+func (t *typer) call(n ast.Node, f *function) (ast.Node, string) {
+	node := n.Value.(ast.CallExpr)
+	args := make([]ast.Node, len(node.Args))
+	types := make([]string, len(node.Args))
+	for k, v := range node.Args {
+		args[k], types[k] = t.expression(v, f)
+	}
+
+	var params, results []string
+	if callee, found := t.funcs[node.Name]; found {
+		for k, v := range names(callee.decl.Params) {
+			if k < len(types) {
+				t.assign(callee, v, types[k])
+			}
+			params = append(params, callee.locals[v])
+		}
+		for _, v := range names(callee.decl.Results) {
+			results = append(results, callee.locals[v])
+		}
+	} else if e, found := t.externs[node.Name]; found {
+		params, results = e.Params, e.Results
+	}
+
+	for k := range args {
+		if k >= len(params) {
+			// argument count is verified when code is emitted
+			break
+		}
+		var ok bool
+		args[k], ok = t.convert(args[k], types[k], params[k])
+		if !ok {
+			t.errorf(n, diagnostics.ArgumentType,
+				"function %v argument %v: can't use %v as %v",
+				node.Name, k+1, name(types[k]), name(params[k]))
+		}
+	}
+
+	result := ""
+	if len(results) == 1 && results[0] != ast.TypeAny {
+		result = results[0]
+	}
+	return ast.NewCall(n.Debug, node.Name, args), result
+}
+
+// expression infers the type of expression n in function f, nil outside of
+// functions.
+// It returns n with the conversions that are required during the final pass.
+func (t *typer) expression(n ast.Node, f *function) (ast.Node, string) {
+	switch node := n.Value.(type) {
+	case ast.NodeInteger:
+		return n, ast.TypeInt
+	case ast.NodeNumber:
+		return n, ast.TypeNumber
+	case ast.NodeString:
+		return n, ast.TypeString
+	case ast.NodeBool:
+		return n, ast.TypeBool
+	case ast.NodeIdentifier:
+		return n, t.vars(f, node.Value)[node.Value]
+	case ast.UnaryExpr:
+		x, xt := t.expression(node.X, f)
+		want, result := ast.TypeBool, ast.TypeBool
+		switch node.Op {
+		case ast.Len:
+			want, result = ast.TypeString, ast.TypeInt
+		case ast.Uminus:
+			want, result = xt, xt
+			if xt != "" && !numeric(xt) {
+				want = ast.TypeNumber
+			}
+		}
+		if xt != "" && xt != want {
+			t.errorf(n, diagnostics.InvalidOperation,
+				"invalid operation: %v %v",
+				ast.OpString(node.Op), name(xt))
+		}
+		return ast.NewUnary(n.Debug, node.Op, x), result
+	case ast.BinaryExpr:
+		return t.binary(n, f)
+	case ast.CallExpr:
+		return t.call(n, f)
+	}
+	return n, ""
+}
+
+// condition infers the type of the condition of an if or while statement.
+func (t *typer) condition(n ast.Node, f *function) ast.Node {
+	c, ct := t.expression(n, f)
+	if ct != "" && ct != ast.TypeBool {
+		t.errorf(n, diagnostics.TypeMismatch,
+			"condition must be bool, got %v", name(ct))
+	}
+	return c
+}
+
+// statement infers the types of the variables that are assigned in n.
+// It returns n with the conversions that are required during the final pass.
+func (t *typer) statement(n ast.Node, f *function) ast.Node {
+	switch node := n.Value.(type) {
+	case ast.AssignStmt:
+		value, vt := t.expression(node.Value, f)
+		targets := names(node.Targets)
+		types := []string{vt}
+		if len(targets) > 1 {
+			types = t.results(node.Value)
+		}
+		for k, id := range targets {
+			if k >= len(types) {
+				break
+			}
+			t.assign(f, id, types[k])
+			want := t.vars(f, id)[id]
+			var ok bool
+			if len(targets) == 1 {
+				value, ok = t.convert(value, types[k], want)
+			} else {
+				// results of a multiple assignment can't be
+				// converted
+				ok = types[k] == "" || want == types[k]
+			}
+			if !ok {
+				t.errorf(n, diagnostics.TypeMismatch,
+					"can't assign %v to %v variable %v",
+					name(types[k]), name(want), id)
+			}
+		}
+		return ast.NewAssign(n.Debug, node.Targets, value)
+	case ast.DiscardStmt:
+		x, _ := t.expression(node.X, f)
+		return ast.NewDiscard(n.Debug, x)
+	case ast.IfNode:
+		cond := t.condition(node.Cond, f)
+		then := t.statement(node.Then, f)
+		els := node.Else
+		if els.Value != nil {
+			els = t.statement(els, f)
+		}
+		return ast.NewIf(n.Debug, cond, then, els)
+	case ast.WhileNode:
+		cond := t.condition(node.Cond, f)
+		return ast.NewWhile(n.Debug, cond, t.statement(node.Body, f))
+	case ast.FuncDecl:
+		fn := t.funcs[node.Name]
+		if fn == nil {
+			return n
+		}
+		body := t.statement(node.Body, fn)
+		return ast.NewFunc(n.Debug, node.Name, node.Params,
+			node.Results, body)
+	case ast.NodeOperand:
+		nodes := make([]ast.Node, 0, len(node.Nodes))
+		for _, v := range node.Nodes {
+			nodes = append(nodes, t.statement(v, f))
+		}
+		return ast.NewOperand(n.Debug, node.Operand, nodes...)
+	}
+	return n
+}
+
+// results returns the result types of call n.
+func (t *typer) results(n ast.Node) []string {
+	node, ok := n.Value.(ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	if callee, found := t.funcs[node.Name]; found {
+		var r []string
+		for _, v := range names(callee.decl.Results) {
+			r = append(r, callee.locals[v])
+		}
+		return r
+	}
+	var r []string
+	for _, v := range t.externs[node.Name].Results {
+		if v == ast.TypeAny {
+			v = ""
+		}
+		r = append(r, v)
+	}
+	return r
+}
+
+// TypeCheck infers the types of the expressions and variables in AST n and
+// verifies that they are used consistently.
+// Variables have the type of the values that are assigned to them and
+// function parameters have the type of the arguments they are called with.
+// An integer is converted to a number where a number is required; all other
+// mismatches are errors.
+// Calls to functions that are not defined in n are resolved with externs.
+// It returns n with the conversions or a diagnostics.List.
+func TypeCheck(n ast.Node, externs ...ast.Extern) (ast.Node, error) {
+	t := typer{
+		externs: make(map[string]ast.Extern),
+		funcs:   make(map[string]*function),
+		globals: make(map[string]string),
+	}
+	for _, v := range externs {
+		t.externs[v.Name] = v
+	}
+	ast.Inspect(n, func(v ast.Node) bool {
+		f, ok := v.Value.(ast.FuncDecl)
+		if !ok {
+			return true
+		}
+		if _, found := t.funcs[f.Name]; !found {
+			t.funcs[f.Name] = &function{
+				decl:   f,
+				scope:  newScope(f),
+				locals: make(map[string]string),
+			}
+		}
+		return false
+	})
+
+	for k := 0; k < maxPasses; k++ {
+		t.changed = false
+		t.statement(n, nil)
+		if !t.changed {
+			break
+		}
+	}
+	t.final = true
+	n = t.statement(n, nil)
+
+	if len(t.diags) != 0 {
+		return n, sorted(t.diags)
+	}
+	return n, nil
+}
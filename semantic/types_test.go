@@ -0,0 +1,124 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/marcopeereboom/gck/ast"
+	"github.com/marcopeereboom/gck/diagnostics"
+	"github.com/marcopeereboom/gck/frontend"
+)
+
+var osNumber = ast.Extern{
+	Name:    NumberConversion,
+	Params:  []string{ast.TypeAny},
+	Results: []string{ast.TypeNumber},
+}
+
+// typeCheck compiles src in language lang and returns the converted AST and
+// the diagnostic codes of TypeCheck.
+func typeCheck(t *testing.T, lang, src string) (ast.Node,
+	[]diagnostics.Code) {
+
+	fe, err := frontend.New(lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fe.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fe.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err = TypeCheck(a, osPrint, osNumber)
+	var codes []diagnostics.Code
+	for _, v := range diagnostics.FromError(err, diagnostics.Unknown) {
+		codes = append(codes, v.Code)
+	}
+	return a, codes
+}
+
+// calls returns the number of calls to function name in n.
+func calls(n ast.Node, name string) int {
+	c := 0
+	ast.Inspect(n, func(v ast.Node) bool {
+		if call, ok := v.Value.(ast.CallExpr); ok && call.Name == name {
+			c++
+		}
+		return true
+	})
+	return c
+}
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		lang  string
+		src   string
+		conv  int // number of runtime conversions
+		codes []diagnostics.Code
+	}{
+		{
+			// constants are converted at compile time
+			lang: frontend.SML,
+			src:  "x = 1 + 2.5;\n",
+		},
+		{
+			lang: frontend.SML,
+			src:  "i = 1;\nx = i * 2.5;\n",
+			conv: 1,
+		},
+		{
+			// x widens to a number
+			lang: frontend.SML,
+			src:  "x = 1;\nx = 0.5;\n",
+		},
+		{
+			lang:  frontend.SML,
+			src:   "x = \"a\" - 1;\n",
+			codes: []diagnostics.Code{diagnostics.InvalidOperation},
+		},
+		{
+			lang:  frontend.SML,
+			src:   "if 1 { x = 1; }\n",
+			codes: []diagnostics.Code{diagnostics.TypeMismatch},
+		},
+		{
+			lang:  frontend.SML,
+			src:   "x = 1;\nx = true;\n",
+			codes: []diagnostics.Code{diagnostics.TypeMismatch},
+		},
+		{
+			// parameters have the type of their arguments
+			lang: frontend.MYRMIDON,
+			src: "func f (a) (b) {\n\tb = a;\n}\n" +
+				"func main () () {\n\ti = 1;\n\tx = f(i);\n" +
+				"\ty = f(0.5);\n\tos.print(x);\n\tos.print(y);\n}\n",
+			conv: 1,
+		},
+		{
+			lang: frontend.MYRMIDON,
+			src: "func f (a) (b) {\n\tb = a;\n}\n" +
+				"func main () () {\n\tx = f(1);\n" +
+				"\ty = f(\"s\");\n}\n",
+			codes: []diagnostics.Code{diagnostics.ArgumentType},
+		},
+	}
+	for k, v := range tests {
+		a, codes := typeCheck(t, v.lang, v.src)
+		if len(codes) != len(v.codes) {
+			t.Fatalf("%v: got %v, want %v", k, codes, v.codes)
+		}
+		for kk, vv := range codes {
+			if vv != v.codes[kk] {
+				t.Fatalf("%v: got %v, want %v", k, codes, v.codes)
+			}
+		}
+		if v.codes != nil {
+			continue
+		}
+		if c := calls(a, NumberConversion); c != v.conv {
+			t.Fatalf("%v: got %v conversions, want %v", k, c, v.conv)
+		}
+	}
+}
@@ -35,6 +35,14 @@ func NewConst(id uint64, name string, value interface{}) (*Const, error) {
 		v.Type = SymIntId
 		v.value = av
 		v.Value = strconv.Itoa(av)
+	case string:
+		v.Type = SymStringId
+		v.value = av
+		v.Value = av
+	case bool:
+		v.Type = SymBoolId
+		v.value = av
+		v.Value = strconv.FormatBool(av)
 	case uint64:
 		v.Type = SymLabelId
 		v.value = av
@@ -63,6 +71,12 @@ func encodeConstElement(v *Const) ([]byte, error) {
 	case int:
 		vv.Type = SymIntId
 		vv.Value = strconv.Itoa(val)
+	case string:
+		vv.Type = SymStringId
+		vv.Value = val
+	case bool:
+		vv.Type = SymBoolId
+		vv.Value = strconv.FormatBool(val)
 	case uint64:
 		vv.Type = SymLabelId
 		v.Value = fmt.Sprintf("%v", val)
@@ -112,6 +126,14 @@ func decodeConstElement(b []byte, consumed *int) (*Const, error) {
 		if err != nil {
 			return nil, err
 		}
+	case SymStringId:
+		v.value = v.Value
+	case SymBoolId:
+		var err error
+		v.value, err = strconv.ParseBool(v.Value)
+		if err != nil {
+			return nil, err
+		}
 	case SymLabelId:
 		newConst, err := strconv.Atoi(v.Value)
 		if err != nil {
@@ -0,0 +1,83 @@
+package section
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/davecgh/go-xdr/xdr2"
+)
+
+// Line maps the code that starts at PC to a line in the source.
+type Line struct {
+	PC     uint64 // first instruction of the line
+	LineNo uint64 // source line number
+	Text   string // source line
+}
+
+// Debug is an xdr representation of the debug information of an image.
+// It is optional and only used to describe runtime errors.
+type Debug struct {
+	Lines []Line // ordered by PC
+}
+
+func encodeDebug(d *Debug) ([]byte, error) {
+	for k := 1; k < len(d.Lines); k++ {
+		if d.Lines[k].PC < d.Lines[k-1].PC {
+			return nil, fmt.Errorf("debug lines not ordered at "+
+				"0x%0x", d.Lines[k].PC)
+		}
+	}
+
+	var w bytes.Buffer
+	_, err := xdr.Marshal(&w, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+func decodeDebug(b []byte) (*Debug, error) {
+	d := Debug{}
+	n, err := xdr.Unmarshal(bytes.NewReader(b), &d)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(b) {
+		return nil, fmt.Errorf("trailing debug data")
+	}
+
+	return &d, nil
+}
+
+func NewDebugSection(d *Debug) (*Section, error) {
+	// make sure it is valid
+	_, err := encodeDebug(d)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := Section{
+		Version: Version,
+		Name:    Sections[DebugId],
+		Id:      DebugId,
+		Read:    true,
+		Write:   false,
+		Execute: false,
+		Payload: d,
+	}
+	return &ds, nil
+}
+
+// Line returns the source line of the instruction at pc.
+// The line number is 0 if it is unknown.
+func (d *Debug) Line(pc uint64) Line {
+	var line Line
+	for _, l := range d.Lines {
+		if l.PC > pc {
+			break
+		}
+		line = l
+	}
+	return line
+}
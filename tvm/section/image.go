@@ -39,6 +39,8 @@ func (i *Image) AddSection(s *Section, compress bool) error {
 	case VariableId:
 	case ConstId:
 	case OsId:
+	case StateId:
+	case DebugId:
 	default:
 		return fmt.Errorf("invalid image section id 0x%0x", s.Id)
 	}
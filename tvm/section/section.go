@@ -21,6 +21,8 @@ const (
 	ConstId    = 3
 	VariableId = 4
 	OsId       = 5
+	StateId    = 6
+	DebugId    = 7
 
 	FExecute  = 1 << 0
 	FWrite    = 1 << 1
@@ -36,6 +38,8 @@ var (
 		ConstId:    ".CONST",
 		VariableId: ".VAR",
 		OsId:       ".OS",
+		StateId:    ".STATE",
+		DebugId:    ".DEBUG",
 	}
 )
 
@@ -158,6 +162,30 @@ func (s *Section) Raw(compress bool) ([]byte, error) {
 				p, Sections[s.Id])
 		}
 
+	case *State:
+		switch s.Id {
+		case StateId:
+			image, err = encodeState(p)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("invalid type %T for section %v",
+				p, Sections[s.Id])
+		}
+
+	case *Debug:
+		switch s.Id {
+		case DebugId:
+			image, err = encodeDebug(p)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("invalid type %T for section %v",
+				p, Sections[s.Id])
+		}
+
 	default:
 		return nil, fmt.Errorf("unknown section id 0x%x", s.Id)
 	}
@@ -257,6 +285,10 @@ func SectionFromImage(image []byte, consumed *int) (*Section, error) {
 		s.Name = Sections[ConstId]
 	case OsId:
 		s.Name = Sections[OsId]
+	case StateId:
+		s.Name = Sections[StateId]
+	case DebugId:
+		s.Name = Sections[DebugId]
 	default:
 		return nil, fmt.Errorf("invalid image segment id 0x%x", s.Id)
 	}
@@ -273,18 +305,15 @@ func SectionFromImage(image []byte, consumed *int) (*Section, error) {
 	}
 
 	// read image
+	seek, err := b.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		size int
-		seek int64
 		ir   io.Reader
-		err  error
 	)
 	if h.Flags&FCompress == FCompress {
-		seek, err = b.Seek(0, 1)
-		if err != nil {
-			return nil, err
-		}
-
 		size, err = uncompressedSize(image[seek:])
 		if err != nil {
 			return nil, err
@@ -304,7 +333,7 @@ func SectionFromImage(image []byte, consumed *int) (*Section, error) {
 
 	// calculate digest
 	blob := make([]byte, size)
-	_, err = ir.Read(blob)
+	_, err = io.ReadFull(ir, blob)
 	if err != nil {
 		return nil, err
 	}
@@ -341,6 +370,22 @@ func SectionFromImage(image []byte, consumed *int) (*Section, error) {
 		}
 
 		s.Payload = osc
+
+	case StateId:
+		state, err := decodeState(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Payload = state
+
+	case DebugId:
+		debug, err := decodeDebug(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Payload = debug
 	default:
 		// can't happen due to test above
 		return nil, fmt.Errorf("invalid segment id 0x%x", s.Id)
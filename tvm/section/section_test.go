@@ -61,6 +61,29 @@ func TestVariable(t *testing.T) {
 	}
 }
 
+func TestString(t *testing.T) {
+	c, err := NewConst(1000, "Moo", "moo \"cow\"\n")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ce, err := encodeConstElement(c)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	cd, err := decodeConstElement(ce, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(c, cd) {
+		t.Errorf("consts not equal")
+		t.Logf("%v %v", c, cd)
+		return
+	}
+}
+
 func TestNewUncompressed(t *testing.T) {
 	image := []uint64{
 		0,
@@ -160,7 +183,7 @@ func TestNewCompressedVariableSection(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	dvs = dvs // shut up
+	_ = dvs // shut up
 	// this does not test the variables
 	//if !reflect.DeepEqual(vs, dvs) {
 	//	t.Errorf("uncompressed test corrupt")
@@ -230,5 +253,62 @@ func TestMulti(t *testing.T) {
 	}
 
 	// add corruption test here
-	sections = sections // shut compiler up for now
+	_ = sections // shut compiler up for now
+}
+
+func TestBool(t *testing.T) {
+	v, err := NewVariable(1000, "b", true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ve, err := encodeVariableElement(v)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	vd, err := decodeVariableElement(ve, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(v, vd) {
+		t.Errorf("variables not equal")
+		t.Logf("%v %v", v, vd)
+		return
+	}
+}
+
+func TestOsCall(t *testing.T) {
+	oc := OsCall{
+		Id:   1000,
+		Name: "os.moo",
+		Variables: []OsInOut{
+			{Name: "a"},
+			{Name: "b", Type: reflect.TypeOf("")},
+		},
+		Results: []OsInOut{
+			{Name: "c", Type: reflect.TypeOf(new(big.Rat))},
+		},
+	}
+	o, err := NewOs(oc.Id, oc.Name, oc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	oe, err := encodeOsElement(o)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	od, err := decodeOsElement(oe, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(o, od) {
+		t.Errorf("os calls not equal")
+		t.Logf("%v %v", o, od)
+		return
+	}
 }
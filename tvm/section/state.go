@@ -0,0 +1,85 @@
+package section
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/davecgh/go-xdr/xdr2"
+)
+
+// StateVersion is the version of the state section format.
+// It is independent of the image version so that snapshots can evolve
+// without invalidating images.
+const StateVersion = 1
+
+// Ref is the reference counter of a symbol.
+type Ref struct {
+	Id   uint64
+	RefC int64
+}
+
+// State is an xdr representation of the state of a virtual machine.
+// A snapshot is an image that contains the code, the symbol table and a state
+// section.
+type State struct {
+	Version      uint64   // state format version
+	PC           uint64   // program counter
+	Stack        []uint64 // command stack
+	CallStack    []uint64 // call stack
+	Locals       []uint64 // local stack
+	FP           uint64   // frame pointer
+	Refs         []Ref    // reference counters of the symbols
+	Zero         uint64   // number of symbols without references
+	GC           uint64   // number of GCs run
+	Instructions uint64   // number of instructions run
+}
+
+func encodeState(s *State) ([]byte, error) {
+	if s.Version != StateVersion {
+		return nil, fmt.Errorf("invalid state version %v", s.Version)
+	}
+
+	var w bytes.Buffer
+	_, err := xdr.Marshal(&w, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+func decodeState(b []byte) (*State, error) {
+	s := State{}
+	n, err := xdr.Unmarshal(bytes.NewReader(b), &s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Version != StateVersion {
+		return nil, fmt.Errorf("invalid state version expected %v "+
+			"got %v", StateVersion, s.Version)
+	}
+	if n != len(b) {
+		return nil, fmt.Errorf("trailing state data")
+	}
+
+	return &s, nil
+}
+
+func NewStateSection(s *State) (*Section, error) {
+	// make sure it is valid
+	_, err := encodeState(s)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := Section{
+		Version: Version,
+		Name:    Sections[StateId],
+		Id:      StateId,
+		Read:    true,
+		Write:   true,
+		Execute: false,
+		Payload: s,
+	}
+	return &ss, nil
+}
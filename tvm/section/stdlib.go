@@ -3,15 +3,19 @@ package section
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 
 	"github.com/davecgh/go-xdr/xdr2"
+	"github.com/marcopeereboom/gck/ast"
 )
 
 // XXX Note that this code is basically a copy/paste from variable.
 // XXX This needs to be refactored
 
+// OsInOut describes an argument or result of an OS call.
+// A nil Type means that any type is accepted.
 type OsInOut struct {
 	Name string
 	Type reflect.Type
@@ -24,18 +28,97 @@ type OsCall struct {
 	Results   []OsInOut
 }
 
+var (
+	// OsTypes maps the type names that are used in the string
+	// representation of an OsCall to the Go types that are exchanged with
+	// the stdlib.
+	// The names are those of compile time externs.
+	OsTypes = map[string]reflect.Type{
+		ast.TypeAny:    nil,
+		ast.TypeInt:    reflect.TypeOf(int(0)),
+		ast.TypeNumber: reflect.TypeOf((*big.Rat)(nil)),
+		ast.TypeString: reflect.TypeOf(""),
+		ast.TypeBool:   reflect.TypeOf(false),
+	}
+)
+
+// OsTypeName returns the name of Go type t as used in OsTypes.
+func OsTypeName(t reflect.Type) (string, error) {
+	for k, v := range OsTypes {
+		if v == t {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported os type %v", t)
+}
+
+func inOutString(io []OsInOut) string {
+	s := make([]string, 0, len(io))
+	for _, v := range io {
+		tn, err := OsTypeName(v.Type)
+		if err != nil {
+			tn = "invalid"
+		}
+		s = append(s, strings.TrimSpace(v.Name+" "+tn))
+	}
+	return strings.Join(s, ",")
+}
+
+// String returns the OsCall signature, e.g. os.print(v any)().
+// The string representation is what is stored in the image.
 func (o *OsCall) String() string {
-	var vars, results string
+	return fmt.Sprintf("%v(%v)(%v)", o.Name, inOutString(o.Variables),
+		inOutString(o.Results))
+}
 
-	// skip types for now
-	for _, v := range o.Variables {
-		vars += v.Name + ","
+func parseInOut(s string) ([]OsInOut, error) {
+	var io []OsInOut
+	if strings.TrimSpace(s) == "" {
+		return io, nil
 	}
-	for _, v := range o.Results {
-		vars += v.Name + ","
+	for _, v := range strings.Split(s, ",") {
+		var name, tn string
+		f := strings.Fields(v)
+		switch len(f) {
+		case 1:
+			tn = f[0]
+		case 2:
+			name, tn = f[0], f[1]
+		default:
+			return nil, fmt.Errorf("invalid os argument: %q", v)
+		}
+		t, found := OsTypes[tn]
+		if !found {
+			return nil, fmt.Errorf("invalid os type: %q", tn)
+		}
+		io = append(io, OsInOut{Name: name, Type: t})
 	}
+	return io, nil
+}
 
-	return fmt.Sprintf("%v(%v)(%v)", o.Name, vars, results)
+// ParseOsCall is the inverse of OsCall.String.
+func ParseOsCall(id uint64, s string) (OsCall, error) {
+	o := OsCall{Id: id}
+
+	// name(variables)(results)
+	a := strings.Index(s, "(")
+	b := strings.Index(s, ")(")
+	if a <= 0 || b < a || !strings.HasSuffix(s, ")") {
+		return o, fmt.Errorf("invalid os call: %q", s)
+	}
+
+	var err error
+	o.Name = s[:a]
+	o.Variables, err = parseInOut(s[a+1 : b])
+	if err != nil {
+		return o, err
+	}
+	o.Results, err = parseInOut(s[b+2 : len(s)-1])
+	if err != nil {
+		return o, err
+	}
+
+	return o, nil
 }
 
 // Os is an xdr representation
@@ -113,10 +196,15 @@ func decodeOsElement(b []byte, consumed *int) (*Os, error) {
 
 	switch v.Type {
 	case SymLabelId:
-		if strings.HasPrefix(v.Value, v.Name) {
-			// XXX good enough for now
-			// XXX enforce args/results count + type
+		oc, err := ParseOsCall(v.Id, v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if oc.Name != v.Name {
+			return nil, fmt.Errorf("os call name mismatch: %v %v",
+				v.Name, oc.Name)
 		}
+		v.value = oc
 
 	default:
 		return nil, fmt.Errorf("unsupported type")
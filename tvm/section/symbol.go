@@ -10,6 +10,8 @@ const (
 	SymLabelId  = 1   // label
 	SymNumId    = 2   // big.Rat
 	SymIntId    = 3   // int
+	SymStringId = 4   // string
+	SymBoolId   = 5   // bool
 	SymReserved = 256 // minimum symbol id
 
 	SymReservedFalse   = 0 // false value
@@ -19,10 +21,12 @@ const (
 
 var (
 	Symbols = map[uint64]string{
-		SymInvalid: "INVALID",
-		SymLabelId: "LABEL",
-		SymNumId:   "NUMBER",
-		SymIntId:   "INTEGER",
+		SymInvalid:  "INVALID",
+		SymLabelId:  "LABEL",
+		SymNumId:    "NUMBER",
+		SymIntId:    "INTEGER",
+		SymStringId: "STRING",
+		SymBoolId:   "BOOL",
 	}
 
 	SymbolsReserved = map[uint64]string{
@@ -117,6 +121,16 @@ func constant(s *Symbol, val interface{}) error {
 		s.Value = v
 		return nil
 
+	case string:
+		s.TypeId = SymStringId
+		s.Value = v
+		return nil
+
+	case bool:
+		s.TypeId = SymBoolId
+		s.Value = v
+		return nil
+
 	default:
 		return fmt.Errorf("invalid type %T", val)
 	}
@@ -137,6 +151,16 @@ func variable(s *Symbol, val interface{}) error {
 		s.Value = v
 		return nil
 
+	case string:
+		s.TypeId = SymStringId
+		s.Value = v
+		return nil
+
+	case bool:
+		s.TypeId = SymBoolId
+		s.Value = v
+		return nil
+
 	default:
 		return fmt.Errorf("invalid type %T", val)
 	}
@@ -33,6 +33,14 @@ func NewVariable(id uint64, name string, value interface{}) (*Variable, error) {
 		v.Type = SymIntId
 		v.value = av
 		v.Value = strconv.Itoa(av)
+	case string:
+		v.Type = SymStringId
+		v.value = av
+		v.Value = av
+	case bool:
+		v.Type = SymBoolId
+		v.value = av
+		v.Value = strconv.FormatBool(av)
 	default:
 		return nil, fmt.Errorf("unsuported type %T", value)
 	}
@@ -57,6 +65,12 @@ func encodeVariableElement(v *Variable) ([]byte, error) {
 	case int:
 		vv.Type = SymIntId
 		vv.Value = strconv.Itoa(val)
+	case string:
+		vv.Type = SymStringId
+		vv.Value = val
+	case bool:
+		vv.Type = SymBoolId
+		vv.Value = strconv.FormatBool(val)
 	default:
 		return nil, fmt.Errorf("unsupported variable type %T", val)
 	}
@@ -103,6 +117,14 @@ func decodeVariableElement(b []byte, consumed *int) (*Variable, error) {
 		if err != nil {
 			return nil, err
 		}
+	case SymStringId:
+		v.value = v.Value
+	case SymBoolId:
+		var err error
+		v.value, err = strconv.ParseBool(v.Value)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unsupported variable type")
 	}
@@ -0,0 +1,136 @@
+package stdlib
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Func is a Go function that can be called from tvm.
+// Arguments are plain Go values: int, *big.Rat, string or bool.
+// A function without results may return a nil Result.
+type Func func(args ...interface{}) (*Result, error)
+
+// Function describes a function that can be called from tvm.
+// A nil argument or result type means that any type is accepted.
+type Function struct {
+	Name    string         // qualified name, e.g. os.print
+	Fn      Func           // function that is called
+	Args    []reflect.Type // argument types
+	Results []reflect.Type // result types
+}
+
+// Registry is a set of functions that can be called from tvm.
+// Every vm has its own registry so that embedding applications can expose
+// their own Go functions.
+type Registry struct {
+	funcs  map[string]*Function
+	output io.Writer // where functions such as print write to
+}
+
+// NewRegistry returns a registry that contains the builtin functions.
+// The builtin functions write their output to os.Stdout.
+func NewRegistry() *Registry {
+	r := Registry{
+		funcs:  make(map[string]*Function),
+		output: os.Stdout,
+	}
+	for k := range builtins {
+		f := builtins[k]
+		r.funcs[f.Name] = &f
+	}
+	r.funcs[Print].Fn = r.print
+	return &r
+}
+
+// SetOutput sets the destination of the functions in the registry that write
+// output, such as print.
+// It must not be called while a program that uses the registry is running.
+func (r *Registry) SetOutput(w io.Writer) {
+	r.output = w
+}
+
+// validType returns true if t can be exchanged with tvm.
+func validType(t reflect.Type) bool {
+	switch t {
+	case nil, reflect.TypeOf(int(0)), reflect.TypeOf((*big.Rat)(nil)),
+		reflect.TypeOf(""), reflect.TypeOf(false):
+		return true
+	}
+	return false
+}
+
+// Register adds function fn to the registry.
+// The name must be qualified, e.g. app.double, and must not already be
+// registered.
+// Argument and result types must be int, *big.Rat, string, bool or nil which
+// accepts any of those.
+func (r *Registry) Register(name string, fn Func, args,
+	results []reflect.Type) error {
+
+	if fn == nil {
+		return fmt.Errorf("nil function %v", name)
+	}
+	if !qualified(name) {
+		return fmt.Errorf("invalid function name: %v", name)
+	}
+	if _, found := r.funcs[name]; found {
+		return fmt.Errorf("function already registered: %v", name)
+	}
+	for _, v := range append(append([]reflect.Type{}, args...),
+		results...) {
+		if !validType(v) {
+			return fmt.Errorf("unsupported type %v for function %v",
+				v, name)
+		}
+	}
+
+	r.funcs[name] = &Function{
+		Name:    name,
+		Fn:      fn,
+		Args:    append([]reflect.Type{}, args...),
+		Results: append([]reflect.Type{}, results...),
+	}
+
+	return nil
+}
+
+// qualified returns true if name has the form package.function.
+func qualified(name string) bool {
+	i := strings.Index(name, ".")
+	return i > 0 && i < len(name)-1 && strings.Count(name, ".") == 1
+}
+
+// Lookup returns the function that is registered as name.
+func (r *Registry) Lookup(name string) (*Function, bool) {
+	f, found := r.funcs[name]
+	return f, found
+}
+
+// Functions returns all registered functions sorted by name.
+func (r *Registry) Functions() []*Function {
+	fs := make([]*Function, 0, len(r.funcs))
+	for _, v := range r.funcs {
+		fs = append(fs, v)
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		return fs[i].Name < fs[j].Name
+	})
+	return fs
+}
+
+// Dispatch calls function name with the provided arguments.
+func (r *Registry) Dispatch(name string, args ...interface{}) (*Result,
+	error) {
+
+	f, found := r.funcs[name]
+	if !found {
+		return nil, fmt.Errorf("stdlib function not found: %v", name)
+	}
+
+	return f.Fn(args...)
+}
@@ -3,7 +3,11 @@
 // For example, a print function could go in here.
 package stdlib
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
 
 type Result struct {
 	Error error         // indicate if call failed or succeeded
@@ -17,36 +21,49 @@ const (
 	RetFalse = "os.false"
 	RetError = "os.error"
 
-	Print = "os.print"
+	Print   = "os.print"
+	Number  = "os.number"
+	Integer = "os.integer"
 )
 
 var (
-	jumpTable = map[string]func(...interface{}) (*Result, error){
+	typeBool   = reflect.TypeOf(false)
+	typeInt    = reflect.TypeOf(int(0))
+	typeNumber = reflect.TypeOf((*big.Rat)(nil))
+
+	// builtins are the functions that every Registry starts out with.
+	builtins = []Function{
 		// test functions
-		RetTrue:  retTrue,
-		RetFalse: retFalse,
-		RetError: retError,
-		Print:    print,
+		{Name: RetTrue, Fn: retTrue, Results: []reflect.Type{typeBool}},
+		{Name: RetFalse, Fn: retFalse, Results: []reflect.Type{typeBool}},
+		{Name: RetError, Fn: retError},
 
 		// actual functions
+		// print is bound to the output of the registry
+		{Name: Print, Args: []reflect.Type{nil}},
+		{Name: Number, Fn: number, Args: []reflect.Type{nil},
+			Results: []reflect.Type{typeNumber}},
+		{Name: Integer, Fn: integer, Args: []reflect.Type{nil},
+			Results: []reflect.Type{typeInt}},
 	}
+
+	// std is the registry that is used by the package level functions.
+	std = NewRegistry()
 )
 
+// GetFunctionNames returns the names of the builtin functions.
 func GetFunctionNames() []string {
-	fn := make([]string, 0, len(jumpTable))
-	for k := range jumpTable {
-		fn = append(fn, k)
+	fn := make([]string, 0, len(builtins))
+	for _, v := range builtins {
+		fn = append(fn, v.Name)
 	}
 	return fn
 }
 
-func Dispatch(name string) (*Result, error) {
-	f, found := jumpTable[name]
-	if !found {
-		return nil, fmt.Errorf("stdlib function not found: %v", name)
-	}
-
-	return f()
+// Dispatch calls builtin function name with the provided arguments.
+// Arguments are plain Go values: int, *big.Rat, string or bool.
+func Dispatch(name string, args ...interface{}) (*Result, error) {
+	return std.Dispatch(name, args...)
 }
 
 func retTrue(args ...interface{}) (*Result, error) {
@@ -61,7 +78,42 @@ func retError(args ...interface{}) (*Result, error) {
 	return nil, fmt.Errorf("returned error")
 }
 
-func print(args ...interface{}) (*Result, error) {
-	fmt.Printf("IN PRINT\n")
-	return &Result{Rv: []interface{}{true}}, nil
+// print writes its argument followed by a newline to the output of the
+// registry.
+func (r *Registry) print(args ...interface{}) (*Result, error) {
+	v := args[0]
+	if n, ok := v.(*big.Rat); ok {
+		v = n.RatString()
+	}
+
+	_, err := fmt.Fprintln(r.output, v)
+	return &Result{Error: err}, nil
+}
+
+// number converts an integer or a number to a number.
+func number(args ...interface{}) (*Result, error) {
+	switch v := args[0].(type) {
+	case int:
+		return &Result{Rv: []interface{}{big.NewRat(int64(v), 1)}}, nil
+	case *big.Rat:
+		return &Result{Rv: []interface{}{new(big.Rat).Set(v)}}, nil
+	}
+	return nil, fmt.Errorf("can't convert %T to number", args[0])
+}
+
+// integer converts a number or an integer to an integer.
+// Numbers are truncated toward zero.
+func integer(args ...interface{}) (*Result, error) {
+	switch v := args[0].(type) {
+	case int:
+		return &Result{Rv: []interface{}{v}}, nil
+	case *big.Rat:
+		i := new(big.Int).Quo(v.Num(), v.Denom())
+		if !i.IsInt64() || int64(int(i.Int64())) != i.Int64() {
+			return nil, fmt.Errorf("%v overflows integer",
+				v.RatString())
+		}
+		return &Result{Rv: []interface{}{int(i.Int64())}}, nil
+	}
+	return nil, fmt.Errorf("can't convert %T to integer", args[0])
 }
@@ -9,12 +9,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"runtime"
+	"time"
 
+	"github.com/marcopeereboom/gck/diagnostics"
 	"github.com/marcopeereboom/gck/tvm/vm"
 )
 
@@ -22,12 +26,28 @@ var (
 	interactive bool
 	trace       bool
 	in          string
+	limits      vm.Limits
+	timeout     time.Duration
 )
 
 func init() {
 	flag.BoolVar(&interactive, "I", false, "run tvm in interactive mode")
 	flag.BoolVar(&trace, "t", false, "dump runtime trace")
 	flag.StringVar(&in, "i", "", "binary image")
+	flag.Uint64Var(&limits.Instructions, "max-instructions", 0,
+		"maximum number of instructions to execute, 0 is unlimited")
+	flag.Uint64Var(&limits.CmdStack, "max-stack", 0,
+		"maximum command stack depth, 0 is unlimited")
+	flag.Uint64Var(&limits.CallStack, "max-calls", 0,
+		"maximum call stack depth, 0 is unlimited")
+	flag.Uint64Var(&limits.Symbols, "max-symbols", 0,
+		"maximum number of live symbols, 0 is unlimited")
+	flag.Uint64Var(&limits.NumberBits, "max-number-bits", 0,
+		"maximum size of a number in bits, 0 is unlimited")
+	flag.Uint64Var(&limits.StringBytes, "max-string-bytes", 0,
+		"maximum size of a string in bytes, 0 is unlimited")
+	flag.DurationVar(&timeout, "timeout", 0,
+		"stop the program after this long, 0 is no timeout")
 }
 
 func _main() error {
@@ -42,6 +62,7 @@ func _main() error {
 	if err != nil {
 		return err
 	}
+	v.SetLimits(limits)
 
 	// see if we want a runtime trace
 	if trace {
@@ -52,7 +73,13 @@ func _main() error {
 	if interactive {
 		err = v.RunInteractive()
 	} else {
-		err = v.Run()
+		ctx := context.Background()
+		if timeout != 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		err = v.RunContext(ctx)
 	}
 	if err != nil {
 		if err != vm.ErrExit {
@@ -93,6 +120,17 @@ func main() {
 	err := _main()
 	if err != nil {
 		fmt.Printf("%v\n", err)
+
+		// print the source line and the calls that lead to a runtime
+		// error
+		var re *vm.RuntimeError
+		if errors.As(err, &re) {
+			var d *diagnostics.Diagnostic
+			if errors.As(re, &d) && d.Context() != "" {
+				fmt.Printf("%v\n", d.Context())
+			}
+			fmt.Printf("\n%v", re.Backtrace())
+		}
 		os.Exit(1)
 	}
 }
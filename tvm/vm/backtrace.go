@@ -0,0 +1,145 @@
+// describe runtime errors with the calls that lead to them
+package vm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcopeereboom/gck/diagnostics"
+	"github.com/marcopeereboom/gck/tvm/section"
+)
+
+// Frame is a function call in a backtrace.
+type Frame struct {
+	Function string // name of the function, empty if unknown
+	Entry    uint64 // location of the function
+	PC       uint64 // location of the failing instruction or of the call
+	LineNo   uint64 // source line of PC, 0 if unknown
+}
+
+// RuntimeError is returned by Run when a program fails.
+// Err is a runtime diagnostic that describes the failure.
+type RuntimeError struct {
+	Err    error   // cause
+	PC     uint64  // location of the failing instruction
+	Frames []Frame // calls that lead to the failure, innermost first
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the runtime diagnostic.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Backtrace returns the frames formatted like a go panic, e.g.:
+//	f()
+//		line 12 +0x1d
+//	main()
+//		line 5 +0x3
+// The offset is relative to the entry of the function.
+// The location is printed when the image has no source lines.
+// Code outside of functions is printed as <entry>.
+func (e *RuntimeError) Backtrace() string {
+	var s string
+	for _, f := range e.Frames {
+		if f.Function == "" {
+			s += "<entry>\n"
+		} else {
+			s += fmt.Sprintf("%v()\n", f.Function)
+		}
+		if f.LineNo != 0 {
+			s += fmt.Sprintf("\tline %v +0x%0x\n", f.LineNo,
+				f.PC-f.Entry)
+		} else {
+			s += fmt.Sprintf("\tpc 0x%0x +0x%0x\n", f.PC,
+				f.PC-f.Entry)
+		}
+	}
+	return s
+}
+
+// function is a label that is the target of OP_JSR.
+type function struct {
+	name  string
+	entry uint64
+}
+
+// functions returns the labels in the .CONST section ordered by location.
+func (v *Vm) functions() []function {
+	var f []function
+	for _, s := range v.sym {
+		if s.SectionId != section.ConstId ||
+			s.TypeId != section.SymLabelId {
+			continue
+		}
+		entry, ok := s.Value.(uint64)
+		if !ok {
+			continue
+		}
+		f = append(f, function{name: s.Name, entry: entry})
+	}
+	sort.Slice(f, func(i, j int) bool {
+		if f[i].entry == f[j].entry {
+			return f[i].name < f[j].name
+		}
+		return f[i].entry < f[j].entry
+	})
+	return f
+}
+
+// frame returns the frame of the function that contains pc.
+// Code before the first function, e.g. the jsr to main, has no name.
+func (v *Vm) frame(functions []function, pc uint64) Frame {
+	f := Frame{PC: pc}
+	k := sort.Search(len(functions), func(i int) bool {
+		return functions[i].entry > pc
+	})
+	if k > 0 {
+		f.Function = functions[k-1].name
+		f.Entry = functions[k-1].entry
+	}
+	if v.debug != nil {
+		f.LineNo = v.debug.Line(pc).LineNo
+	}
+	return f
+}
+
+// backtrace walks the call stack and returns the frames of the current
+// instruction and of the calls that lead to it, innermost first.
+// Calls from code outside of functions, i.e. the call of main, are omitted.
+func (v *Vm) backtrace() []Frame {
+	functions := v.functions()
+	frames := []Frame{v.frame(functions, v.pc)}
+	for k := v.cs - 1; k >= 0; k-- {
+		// return addresses point past the jsr
+		call := v.callStack[k] - vmInstructions[OP_JSR].size
+		if f := v.frame(functions, call); f.Function != "" {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// pos returns the source location of the instruction at pc.
+// The location spans the source line and is unknown if the image has no
+// source lines.
+func (v *Vm) pos(pc uint64) diagnostics.Pos {
+	if v.debug == nil {
+		return diagnostics.Pos{}
+	}
+	line := v.debug.Line(pc)
+	if line.LineNo == 0 {
+		return diagnostics.Pos{}
+	}
+	text := strings.TrimRight(line.Text, " \t")
+	return diagnostics.Pos{
+		LineNo:   int(line.LineNo),
+		ColStart: len(text) - len(strings.TrimLeft(text, " \t")) + 1,
+		ColEnd:   len(text) + 1,
+		Line:     line.Text,
+	}
+}
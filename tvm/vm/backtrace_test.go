@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/marcopeereboom/gck/diagnostics"
+	"github.com/marcopeereboom/gck/tvm/section"
+)
+
+func TestBacktrace(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_JSR,   // 0
+		1002,     // 1
+		OP_EXIT,  // 2
+		OP_ABORT, // 3
+		OP_ABORT, // 4
+		OP_ABORT, // 5
+		OP_ABORT, // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_PUSH,  // 13
+		1007,     // 14
+		OP_PUSH,  // 15
+		1006,     // 16
+		OP_DIV,   // 17 divide by 0
+		OP_RET,   // 18
+	}
+
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := section.NewDebugSection(&section.Debug{
+		Lines: []section.Line{
+			{PC: 0, LineNo: 1},
+			{PC: 13, LineNo: 10, Text: "\tx = 1;"},
+			{PC: 15, LineNo: 11, Text: "\ty = x / 0; "},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.AddSection(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = vm.Run()
+	re, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected runtime error, got %v", err)
+	}
+	var d *diagnostics.Diagnostic
+	if !errors.As(err, &d) || d.Code != diagnostics.Runtime {
+		t.Fatalf("expected runtime diagnostic, got %v", re.Err)
+	}
+	pos := diagnostics.Pos{
+		LineNo:   11,
+		ColStart: 2,
+		ColEnd:   12,
+		Line:     "\ty = x / 0; ",
+	}
+	if d.Pos != pos {
+		t.Fatalf("invalid position %v", d.Pos)
+	}
+
+	// the call of myjsr from outside of a function is omitted
+	frames := []Frame{
+		{Function: "myjsr", Entry: 13, PC: 17, LineNo: 11},
+	}
+	if re.PC != 17 || !reflect.DeepEqual(re.Frames, frames) {
+		t.Fatalf("invalid backtrace at 0x%x: %v", re.PC, re.Frames)
+	}
+	bt := "myjsr()\n\tline 11 +0x4\n"
+	if re.Backtrace() != bt {
+		t.Fatalf("invalid backtrace %q", re.Backtrace())
+	}
+}
+
+func TestBacktraceEntry(t *testing.T) {
+	// fail before any function is called
+	i, err := newImage([]uint64{
+		OP_PUSH, 1007, // 0
+		OP_PUSH, 1006, // 2
+		OP_DIV, // 4 divide by 0
+		OP_EXIT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = vm.Run()
+	re, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected runtime error, got %v", err)
+	}
+	var d *diagnostics.Diagnostic
+	if !errors.As(err, &d) || d.Pos.IsValid() {
+		t.Fatalf("expected diagnostic without position, got %v", re.Err)
+	}
+	bt := "<entry>\n\tpc 0x4 +0x4\n"
+	if re.Backtrace() != bt {
+		t.Fatalf("invalid backtrace %q", re.Backtrace())
+	}
+}
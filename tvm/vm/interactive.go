@@ -67,6 +67,8 @@ func (v *Vm) cmd(cmd vmCommand) vmResponse {
 			r.rv = fmt.Sprintf("%v", v.GetStack(true, VmCmdStack))
 		case "cs":
 			r.rv = fmt.Sprintf("%v", v.GetStack(true, VmCallStack))
+		case "l":
+			r.rv = fmt.Sprintf("%v", v.GetStack(true, VmLocalStack))
 		case "getbreak":
 			r.rv = fmt.Sprintf("%v", v.GetBreak())
 		case "next":
@@ -126,6 +128,7 @@ func (v *Vm) RunInteractive() error {
 				fmt.Printf("sym, symbols - dump symbol table\n")
 				fmt.Printf("s, stack - dump stack\n")
 				fmt.Printf("cs, callstack - dump call stack\n")
+				fmt.Printf("l, locals - dump local stack\n")
 				fmt.Printf("gc, garbagecollect - run GC\n")
 				fmt.Printf("b, break - set/unset or list breakpoints\n")
 				fmt.Printf("c, continue - resume execution\n")
@@ -195,6 +198,12 @@ func (v *Vm) RunInteractive() error {
 				} else {
 					fmt.Printf("%v", v.GetStack(true, VmCallStack))
 				}
+			case "l", "locals":
+				if running {
+					cmd <- vmCommand{cmd: "l"}
+				} else {
+					fmt.Printf("%v", v.GetStack(true, VmLocalStack))
+				}
 			case "gc", "garbagecollect":
 				if running {
 					cmd <- vmCommand{cmd: "gc"}
@@ -0,0 +1,121 @@
+// bound the resources that a program may use
+package vm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/marcopeereboom/gck/tvm/section"
+)
+
+// Limit identifies an execution limit.
+type Limit int
+
+const (
+	InstructionLimit Limit = iota // instructions executed
+	CmdStackLimit                 // command stack depth
+	CallStackLimit                // call stack depth
+	SymbolLimit                   // live symbols
+	NumberLimit                   // size of a number
+	StringLimit                   // size of a string
+)
+
+var limits = map[Limit]string{
+	InstructionLimit: "instruction",
+	CmdStackLimit:    "command stack",
+	CallStackLimit:   "call stack",
+	SymbolLimit:      "symbol",
+	NumberLimit:      "number size",
+	StringLimit:      "string size",
+}
+
+func (l Limit) String() string {
+	if name, found := limits[l]; found {
+		return name
+	}
+	return fmt.Sprintf("limit(%d)", int(l))
+}
+
+// Limits bounds the resources that a program may use so that untrusted code
+// can be run safely.
+// A limit of 0 means unlimited.
+type Limits struct {
+	Instructions uint64 // instructions executed
+	CmdStack     uint64 // command stack depth
+	CallStack    uint64 // call stack depth, this bounds the locals as well
+	Symbols      uint64 // live symbols, including those of the image
+	NumberBits   uint64 // bits of the numerator plus denominator of a number
+	StringBytes  uint64 // bytes of a string
+}
+
+// LimitError is returned by Run when a program exceeds one of its limits.
+type LimitError struct {
+	Limit Limit  // exceeded limit
+	Max   uint64 // value of the limit
+	PC    uint64 // location of the offending instruction
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v limit %v exceeded at 0x%0x", e.Limit, e.Max,
+		e.PC)
+}
+
+// SetLimits sets the limits that are enforced while the program runs.
+// By default a program is not limited.
+func (v *Vm) SetLimits(l Limits) {
+	v.limits = l
+}
+
+// exceeded returns a LimitError if n, the current use of limit l, can not
+// grow any further because it reached max.
+func (v *Vm) exceeded(l Limit, max, n uint64) error {
+	if max == 0 || n < max {
+		return nil
+	}
+	return &LimitError{Limit: l, Max: max, PC: v.pc}
+}
+
+// alloc creates a new variable symbol with value val and adds it to the
+// symbol table.
+// It enforces the symbol, number size and string size limits.
+func (v *Vm) alloc(val interface{}) (*section.Symbol, error) {
+	if r, ok := val.(*big.Rat); ok && v.limits.NumberBits != 0 {
+		bits := uint64(r.Num().BitLen() + r.Denom().BitLen())
+		if bits > v.limits.NumberBits {
+			return nil, &LimitError{
+				Limit: NumberLimit,
+				Max:   v.limits.NumberBits,
+				PC:    v.pc,
+			}
+		}
+	}
+	if s, ok := val.(string); ok && v.limits.StringBytes != 0 &&
+		uint64(len(s)) > v.limits.StringBytes {
+		return nil, &LimitError{
+			Limit: StringLimit,
+			Max:   v.limits.StringBytes,
+			PC:    v.pc,
+		}
+	}
+	if v.exceeded(SymbolLimit, v.limits.Symbols, uint64(len(v.sym))) !=
+		nil {
+		// collect garbage before giving up
+		v.GC()
+		err := v.exceeded(SymbolLimit, v.limits.Symbols,
+			uint64(len(v.sym)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := v.GetId()
+	if err != nil {
+		return nil, err
+	}
+	sym, err := section.New(id, section.VariableId, 1, "", val)
+	if err != nil {
+		return nil, err
+	}
+	v.sym[sym.Id] = sym
+	return sym, nil
+}
@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	// recurse forever, pushing a value for every call
+	recurse := []uint64{
+		OP_JSR,   // 0
+		1002,     // 1
+		OP_EXIT,  // 2
+		OP_ABORT, // 3
+		OP_ABORT, // 4
+		OP_ABORT, // 5
+		OP_ABORT, // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_PUSH,  // 13
+		1000,     // 14
+		OP_PUSH,  // 15
+		1000,     // 16
+		OP_ADD,   // 17 keep a new symbol alive
+		OP_JSR,   // 18
+		1002,     // 19
+		OP_RET,   // 20
+	}
+
+	tests := []struct {
+		name   string
+		prog   []uint64
+		limits Limits
+		limit  Limit
+		pc     uint64
+	}{
+		{
+			name:   "instructions",
+			prog:   []uint64{OP_NOP, OP_JMP, 0},
+			limits: Limits{Instructions: 101},
+			limit:  InstructionLimit,
+			pc:     1,
+		},
+		{
+			name:   "command stack",
+			prog:   recurse,
+			limits: Limits{CmdStack: 10},
+			limit:  CmdStackLimit,
+			pc:     15,
+		},
+		{
+			name:   "call stack",
+			prog:   recurse,
+			limits: Limits{CallStack: 10},
+			limit:  CallStackLimit,
+			pc:     18,
+		},
+		{
+			name:   "symbols",
+			prog:   recurse,
+			limits: Limits{Symbols: 20},
+			limit:  SymbolLimit,
+			pc:     17,
+		},
+		{
+			name: "number size",
+			prog: []uint64{
+				OP_PUSH, 1000, // 0
+				OP_PUSH, 1000, // 2
+				OP_MUL,       // 4
+				OP_POP, 1000, // 5
+				OP_JMP, 0, // 7
+			},
+			limits: Limits{NumberBits: 64},
+			limit:  NumberLimit,
+			pc:     4,
+		},
+		{
+			name: "string size",
+			prog: []uint64{
+				OP_PUSH, 1009, // 0 "hello"
+				OP_POP, 1000, // 2
+				OP_PUSH, 1000, // 4
+				OP_PUSH, 1000, // 6
+				OP_ADD,       // 8
+				OP_POP, 1000, // 9
+				OP_JMP, 4, // 11
+			},
+			limits: Limits{StringBytes: 64},
+			limit:  StringLimit,
+			pc:     8,
+		},
+	}
+	for _, v := range tests {
+		i, err := newImage(v.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm, err := New(i.GetImage())
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm.SetLimits(v.limits)
+		err = vm.Run()
+		le, ok := err.(*LimitError)
+		if !ok {
+			t.Fatalf("%v: expected limit error, got %v", v.name, err)
+		}
+		if le.Limit != v.limit || le.PC != v.pc {
+			t.Fatalf("%v: got %v at 0x%x, want %v at 0x%x", v.name,
+				le.Limit, le.PC, v.limit, v.pc)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// save and restore the state of a virtual machine
+package vm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marcopeereboom/gck/tvm/section"
+	"github.com/marcopeereboom/gck/tvm/stdlib"
+)
+
+// Snapshot returns the state of the vm as an image.
+// The image contains the code, the symbol table, including the symbols that
+// were created while running, and a .STATE section with the program counter,
+// the stacks, the reference counters and the statistics counters.
+// Execution can be resumed from it with Restore, which makes it possible to
+// checkpoint long running programs or to go back in time while debugging.
+// Breakpoints, traces and limits are not part of the snapshot.
+// Snapshot must not be called while the vm is running.
+func (v *Vm) Snapshot() ([]byte, error) {
+	// sort symbols for a reproducible image
+	ids := make([]uint64, 0, len(v.sym))
+	for k := range v.sym {
+		ids = append(ids, k)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	state := section.State{
+		Version:      section.StateVersion,
+		PC:           v.pc,
+		Stack:        append([]uint64{}, v.stack[:v.sp]...),
+		CallStack:    append([]uint64{}, v.callStack[:v.cs]...),
+		Locals:       append([]uint64{}, v.locals[:v.lp]...),
+		FP:           uint64(v.fp),
+		Zero:         v.zero,
+		GC:           v.gc,
+		Instructions: v.instructions,
+	}
+	var (
+		vars   []*section.Variable
+		consts []*section.Const
+		oss    []*section.Os
+	)
+	for _, id := range ids {
+		s := v.sym[id]
+		switch s.SectionId {
+		case section.VariableId:
+			nv, err := section.NewVariable(id, s.Name, s.Value)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, nv)
+		case section.ConstId:
+			nc, err := section.NewConst(id, s.Name, s.Value)
+			if err != nil {
+				return nil, err
+			}
+			consts = append(consts, nc)
+		case section.OsId:
+			no, err := section.NewOs(id, s.Name, s.Value)
+			if err != nil {
+				return nil, err
+			}
+			oss = append(oss, no)
+		default:
+			return nil, fmt.Errorf("invalid section 0x%0x for "+
+				"symbol %v", s.SectionId, s.Name)
+		}
+		state.Refs = append(state.Refs, section.Ref{
+			Id:   id,
+			RefC: int64(s.RefC),
+		})
+	}
+
+	sections := []*section.Section{section.NewCodeSection(v.prog)}
+	if len(vars) != 0 {
+		vs, err := section.NewVariableSection(vars)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, vs)
+	}
+	if len(consts) != 0 {
+		cs, err := section.NewConstSection(consts)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, cs)
+	}
+	if len(oss) != 0 {
+		os, err := section.NewOsSection(oss)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, os)
+	}
+	if v.debug != nil {
+		ds, err := section.NewDebugSection(v.debug)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, ds)
+	}
+	ss, err := section.NewStateSection(&state)
+	if err != nil {
+		return nil, err
+	}
+	sections = append(sections, ss)
+
+	i := section.NewImage()
+	for _, s := range sections {
+		err := i.AddSection(s, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return i.GetImage(), nil
+}
+
+// Restore creates a new VM context from snapshot, see Snapshot.
+// The VM can call the builtin stdlib functions.
+// Calling Run resumes execution where the snapshot was taken.
+func Restore(snapshot []byte) (*Vm, error) {
+	return RestoreWithRegistry(snapshot, stdlib.NewRegistry())
+}
+
+// RestoreWithRegistry creates a new VM context from snapshot that calls the
+// functions in registry r.
+// The code is verified and the state is validated against it.
+func RestoreWithRegistry(snapshot []byte, r *stdlib.Registry) (*Vm, error) {
+	sections, err := section.SectionsFromImage(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	// split off state
+	var state *section.State
+	for k, s := range sections {
+		if s.Id != section.StateId {
+			continue
+		}
+		state = s.Payload.(*section.State)
+		sections = append(sections[:k], sections[k+1:]...)
+		break
+	}
+	if state == nil {
+		return nil, fmt.Errorf("not a snapshot, %v section not found",
+			section.Sections[section.StateId])
+	}
+
+	v, err := load(sections, r)
+	if err != nil {
+		return nil, err
+	}
+	err = verify(v.prog, v.sym)
+	if err != nil {
+		return nil, err
+	}
+	err = v.restore(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// stackCopy returns a stack that contains s and that is at least size
+// entries long.
+func stackCopy(s []uint64, size int) []uint64 {
+	if len(s) > size {
+		size = len(s)
+	}
+	stack := make([]uint64, size)
+	copy(stack, s)
+	return stack
+}
+
+// restore validates state and applies it to the vm.
+func (v *Vm) restore(state *section.State) error {
+	vr := verifier{prog: v.prog}
+	err := vr.decode()
+	if err != nil {
+		return err
+	}
+	instruction := func(pc uint64) bool {
+		return pc < uint64(len(v.prog)) && vr.start[pc]
+	}
+
+	// code locations
+	if state.PC != uint64(len(v.prog)) && !instruction(state.PC) {
+		return fmt.Errorf("invalid state pc 0x%0x", state.PC)
+	}
+	for _, ret := range state.CallStack {
+		if !instruction(ret) {
+			return fmt.Errorf("invalid state return address 0x%0x",
+				ret)
+		}
+	}
+
+	// symbol references
+	for _, ref := range state.Refs {
+		s, found := v.sym[ref.Id]
+		if !found {
+			return fmt.Errorf("invalid state reference to symbol "+
+				"%016x", ref.Id)
+		}
+		s.RefC = int(ref.RefC)
+	}
+	for _, id := range state.Stack {
+		if id == section.SymReservedFalse ||
+			id == section.SymReservedTrue {
+			continue
+		}
+		if _, found := v.sym[id]; !found {
+			return fmt.Errorf("invalid state stack symbol %016x",
+				id)
+		}
+	}
+
+	// frames, there is one for every return address
+	saved := make(map[uint64]bool)
+	frames := 0
+	for fp := state.FP; fp > 0; fp = state.Locals[fp-1] {
+		if fp > uint64(len(state.Locals)) || state.Locals[fp-1] >= fp {
+			return fmt.Errorf("invalid state frame pointer %v", fp)
+		}
+		saved[fp-1] = true
+		frames++
+	}
+	if frames != len(state.CallStack) {
+		return fmt.Errorf("invalid state %v frames for %v calls",
+			frames, len(state.CallStack))
+	}
+	for k, id := range state.Locals {
+		if saved[uint64(k)] || id == 0 {
+			continue
+		}
+		if _, found := v.sym[id]; !found {
+			return fmt.Errorf("invalid state local symbol %016x",
+				id)
+		}
+	}
+
+	v.pc = state.PC
+	v.stack = stackCopy(state.Stack, vmInitialStackSize)
+	v.sp = len(state.Stack)
+	v.callStack = stackCopy(state.CallStack, vmInitialCallStackSize)
+	v.cs = len(state.CallStack)
+	v.locals = stackCopy(state.Locals, vmInitialLocalStackSize)
+	v.lp = len(state.Locals)
+	v.fp = int(state.FP)
+	v.zero = state.Zero
+	v.gc = state.GC
+	v.instructions = state.Instructions
+
+	return nil
+}
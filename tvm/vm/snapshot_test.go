@@ -0,0 +1,171 @@
+package vm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH, // 0
+		1006,    // 1 set 0
+		OP_POP,  // 2
+		1005,    // 3
+		OP_PUSH, // 4 loop body
+		1005,    // 5
+		OP_PUSH, // 6
+		1007,    // 7 add 1 to counter
+		OP_ADD,  // 8
+		OP_POP,  // 9
+		1005,    // 10
+		OP_PUSH, // 11
+		1005,    // 12
+		OP_PUSH, // 13
+		1008,    // 14
+		OP_LT,   // 15
+		OP_BRT,  // 16
+		4,       // 17 jump to loop body
+	}
+
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stop at the first backward jump
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = vm.RunContext(ctx)
+	if _, ok := err.(*StopError); !ok {
+		t.Fatalf("expected stop error, got %v", err)
+	}
+	snapshot, err := vm.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// run the original to completion, the snapshot must not change
+	err = vm.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// resume twice from the same snapshot
+	for k := 0; k < 2; k++ {
+		rvm, err := Restore(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rvm.pc != 16 || rvm.sp != 1 ||
+			rvm.sym[1005].Value.(int) != 1 {
+			t.Fatalf("restored at 0x%x with %v values and i %v",
+				rvm.pc, rvm.sp, rvm.sym[1005].Value)
+		}
+		err = rvm.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rvm.sym[1005].Value.(int) != 5 {
+			t.Fatalf("invalid counter %v", rvm.sym[1005].Value)
+		}
+		if rvm.instructions != vm.instructions {
+			t.Fatalf("ran %v instructions, want %v",
+				rvm.instructions, vm.instructions)
+		}
+	}
+}
+
+func TestSnapshotFrames(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,  // 0
+		1005,     // 1
+		OP_JSR,   // 2
+		1002,     // 3
+		OP_POP,   // 4 store result
+		1005,     // 5
+		OP_EXIT,  // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_ENTER, // 13 f(n) = n < 1 ? n : f(n - 1)
+		1,        // 14
+		OP_POPL,  // 15
+		0,        // 16
+		OP_PUSHL, // 17
+		0,        // 18
+		OP_PUSH,  // 19
+		1007,     // 20
+		OP_LT,    // 21
+		OP_BRF,   // 22
+		27,       // 23
+		OP_PUSHL, // 24
+		0,        // 25
+		OP_RET,   // 26
+		OP_PUSHL, // 27
+		0,        // 28
+		OP_PUSH,  // 29
+		1007,     // 30
+		OP_SUB,   // 31
+		OP_JSR,   // 32
+		1002,     // 33
+		OP_RET,   // 34
+	}
+
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stop in the innermost call
+	vm.SetLimits(Limits{Instructions: 14})
+	err = vm.Run()
+	if _, ok := err.(*LimitError); !ok {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	if vm.cs != 2 {
+		t.Fatalf("stopped with %v calls", vm.cs)
+	}
+	snapshot, err := vm.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// limits are not part of the snapshot
+	rvm, err := Restore(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rvm.cs != 2 || rvm.fp != vm.fp || rvm.lp != vm.lp {
+		t.Fatalf("restored %v calls fp %v lp %v, want 2 fp %v lp %v",
+			rvm.cs, rvm.fp, rvm.lp, vm.fp, vm.lp)
+	}
+	err = rvm.Run()
+	if err != ErrExit {
+		t.Fatal(err)
+	}
+	if rvm.sym[1005].Value.(int) != 0 {
+		t.Fatalf("invalid result %v", rvm.sym[1005].Value)
+	}
+}
+
+func TestRestoreImage(t *testing.T) {
+	i, err := newImage([]uint64{OP_EXIT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Restore(i.GetImage())
+	if err == nil {
+		t.Fatal("restored image without state")
+	}
+}
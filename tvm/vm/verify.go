@@ -0,0 +1,353 @@
+// verify the code section before it is handed to vonNeumann
+package vm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marcopeereboom/gck/tvm/section"
+)
+
+// VerifyError is returned by New when the code section of an image is
+// invalid.
+type VerifyError struct {
+	PC  uint64 // location of the offending instruction
+	Err string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("invalid code at 0x%0x: %v", e.PC, e.Err)
+}
+
+// state is the state of the machine before an instruction is executed.
+// The command stack depth is relative to the entry point of the code that is
+// being verified.
+type state struct {
+	depth  int // command stack depth
+	locals int // allocated local variable slots
+}
+
+// summary describes the command stack effect of a subroutine.
+type summary struct {
+	returns bool // a ret is reachable
+	net     int  // command stack depth at ret
+	min     int  // lowest command stack depth
+}
+
+// verifier is the bytecode verification context.
+type verifier struct {
+	prog    []uint64
+	sym     map[uint64]*section.Symbol
+	start   []bool              // instruction starts
+	subs    map[uint64]*summary // subroutines by entry point
+	entries []uint64            // sorted subroutine entry points
+}
+
+// errorf returns a VerifyError for the instruction at pc.
+func errorf(pc uint64, format string, args ...interface{}) error {
+	return &VerifyError{PC: pc, Err: fmt.Sprintf(format, args...)}
+}
+
+// decode walks the code and records where instructions start.
+func (vr *verifier) decode() error {
+	vr.start = make([]bool, len(vr.prog))
+	for pc := uint64(0); pc < uint64(len(vr.prog)); {
+		i := vr.prog[pc]
+		if i >= OP_INVALID {
+			return errorf(pc, "illegal instruction 0x%0x", i)
+		}
+		if pc+vmInstructions[i].size > uint64(len(vr.prog)) {
+			return errorf(pc, "truncated %v", vmInstructions[i].name)
+		}
+		vr.start[pc] = true
+		pc += vmInstructions[i].size
+	}
+	return nil
+}
+
+// target verifies that the instruction at pc jumps to the start of an
+// instruction.
+func (vr *verifier) target(pc, location uint64) error {
+	name := vmInstructions[vr.prog[pc]].name
+	if location >= uint64(len(vr.prog)) {
+		return errorf(pc, "%v out of bounds 0x%0x", name, location)
+	}
+	if !vr.start[location] {
+		return errorf(pc, "%v into instruction at 0x%0x", name,
+			location)
+	}
+	return nil
+}
+
+// symbol returns the symbol that is the argument of the instruction at pc.
+func (vr *verifier) symbol(pc uint64) (*section.Symbol, error) {
+	s, found := vr.sym[vr.prog[pc+1]]
+	if !found {
+		return nil, errorf(pc, "%v symbol not found 0x%016x",
+			vmInstructions[vr.prog[pc]].name, vr.prog[pc+1])
+	}
+	return s, nil
+}
+
+// operand verifies the argument of the instruction at pc against the symbol
+// table.
+// Subroutine entry points are recorded.
+func (vr *verifier) operand(pc uint64) error {
+	arg := uint64(0)
+	if vmInstructions[vr.prog[pc]].size > 1 {
+		arg = vr.prog[pc+1]
+	}
+
+	switch vr.prog[pc] {
+	case OP_PUSH:
+		if arg == section.SymReservedFalse ||
+			arg == section.SymReservedTrue {
+			return nil
+		}
+		s, err := vr.symbol(pc)
+		if err != nil {
+			return err
+		}
+		if s.SectionId != section.VariableId &&
+			s.SectionId != section.ConstId {
+			return errorf(pc, "can't push %v symbol %v",
+				section.Sections[s.SectionId], s.Name)
+		}
+		if s.TypeId == section.SymLabelId {
+			return errorf(pc, "can't push label %v", s.Name)
+		}
+
+	case OP_POP:
+		if arg == section.SymReservedDiscard {
+			return nil
+		}
+		s, err := vr.symbol(pc)
+		if err != nil {
+			return err
+		}
+		if s.SectionId != section.VariableId {
+			return errorf(pc, "can't pop to %v symbol %v",
+				section.Sections[s.SectionId], s.Name)
+		}
+
+	case OP_JSR:
+		s, err := vr.symbol(pc)
+		if err != nil {
+			return err
+		}
+		if s.SectionId != section.ConstId ||
+			s.TypeId != section.SymLabelId {
+			return errorf(pc, "jsr can not jump using %v %v %v",
+				section.Sections[s.SectionId],
+				section.Symbols[s.TypeId], s.Name)
+		}
+		location := s.Value.(uint64)
+		if err := vr.target(pc, location); err != nil {
+			return err
+		}
+		if pc+2 >= uint64(len(vr.prog)) {
+			return errorf(pc, "jsr return value out of bounds")
+		}
+		if vr.subs[location] == nil {
+			vr.subs[location] = &summary{}
+			vr.entries = append(vr.entries, location)
+		}
+
+	case OP_CALL:
+		s, err := vr.symbol(pc)
+		if err != nil {
+			return err
+		}
+		if s.SectionId != section.OsId {
+			return errorf(pc, "call can not jump to section %v",
+				section.Sections[s.SectionId])
+		}
+		if _, ok := s.Value.(section.OsCall); !ok {
+			return errorf(pc, "call invalid os symbol %v", s.Name)
+		}
+
+	case OP_JMP, OP_BRT, OP_BRF:
+		return vr.target(pc, arg)
+
+	case OP_ENTER:
+		if arg > uint64(len(vr.prog)) {
+			// can't possibly address that many
+			return errorf(pc, "enter too many locals %v", arg)
+		}
+	}
+	return nil
+}
+
+// effect returns the number of values that the instruction at pc pops off
+// and pushes onto the command stack.
+// Subroutine calls are handled by the caller.
+func (vr *verifier) effect(pc uint64) (int, int) {
+	switch vr.prog[pc] {
+	case OP_PUSH, OP_PUSHL:
+		return 0, 1
+	case OP_POP, OP_POPL, OP_BRT, OP_BRF:
+		return 1, 0
+	case OP_ADD, OP_SUB, OP_MUL, OP_DIV, OP_EQ, OP_NEQ, OP_LT, OP_GT,
+		OP_LE, OP_GE:
+		return 2, 1
+	case OP_NEG, OP_LEN, OP_NOT:
+		return 1, 1
+	case OP_CALL:
+		oc := vr.sym[vr.prog[pc+1]].Value.(section.OsCall)
+		return len(oc.Variables), len(oc.Results)
+	}
+	return 0, 0
+}
+
+// analyze follows all paths of the code that starts at entry and verifies
+// that the command stack depth and the number of local variable slots are
+// the same on every path that reaches an instruction.
+// sub is the summary of the subroutine at entry or nil for the program entry
+// point, where the command stack starts out empty.
+// Paths through subroutines that are not known to return yet are not
+// followed.
+// It returns true if the summary changed.
+func (vr *verifier) analyze(entry uint64, sub *summary) (bool, error) {
+	states := map[uint64]state{entry: {}}
+	work := []uint64{entry}
+	changed := false
+	min := 0
+	for len(work) > 0 {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+		s := states[pc]
+		i := vr.prog[pc]
+
+		pops, pushes := vr.effect(pc)
+		low := s.depth - pops
+		next := []uint64{pc + vmInstructions[i].size}
+		switch i {
+		case OP_ABORT, OP_EXIT:
+			next = nil
+		case OP_JMP:
+			next = []uint64{vr.prog[pc+1]}
+		case OP_BRT, OP_BRF:
+			next = append(next, vr.prog[pc+1])
+		case OP_JSR:
+			callee := vr.subs[vr.sym[vr.prog[pc+1]].Value.(uint64)]
+			low = s.depth + callee.min
+			if !callee.returns {
+				next = nil
+			}
+			pushes = callee.net
+		case OP_RET:
+			if sub == nil {
+				return false, errorf(pc, "ret without frame")
+			}
+			if sub.returns && sub.net != s.depth {
+				return false, errorf(pc, "ret with command stack "+
+					"depth %v, expected %v", s.depth, sub.net)
+			}
+			if !sub.returns {
+				sub.returns = true
+				sub.net = s.depth
+				changed = true
+			}
+			next = nil
+		case OP_ENTER:
+			if sub == nil {
+				return false, errorf(pc, "enter outside of "+
+					"subroutine")
+			}
+			s.locals += int(vr.prog[pc+1])
+		case OP_PUSHL, OP_POPL:
+			if slot := vr.prog[pc+1]; slot >= uint64(s.locals) {
+				return false, errorf(pc, "local %v out of bounds",
+					slot)
+			}
+		}
+		if low < min {
+			if sub == nil {
+				return false, errorf(pc, "command stack underflow")
+			}
+			min = low
+		}
+
+		after := state{depth: s.depth - pops + pushes, locals: s.locals}
+		for _, n := range next {
+			if n >= uint64(len(vr.prog)) {
+				// end of program
+				continue
+			}
+			if old, found := states[n]; found {
+				if old.depth != after.depth {
+					return false, errorf(n, "command stack "+
+						"depth %v, expected %v",
+						after.depth, old.depth)
+				}
+				if old.locals != after.locals {
+					return false, errorf(n, "%v local slots, "+
+						"expected %v", after.locals,
+						old.locals)
+				}
+				continue
+			}
+			states[n] = after
+			work = append(work, n)
+		}
+	}
+
+	if sub != nil && min < sub.min {
+		sub.min = min
+		changed = true
+	}
+	return changed, nil
+}
+
+// verify validates the code in prog against symbol table sym before it is
+// executed.
+// It verifies that all instructions are complete, that all operands refer to
+// symbols of the right section and type, that jumps land on instructions and
+// that the command stack can not underflow and has the same depth on every
+// path that reaches an instruction.
+func verify(prog []uint64, sym map[uint64]*section.Symbol) error {
+	vr := verifier{
+		prog: prog,
+		sym:  sym,
+		subs: make(map[uint64]*summary),
+	}
+	err := vr.decode()
+	if err != nil {
+		return err
+	}
+	for pc := uint64(0); pc < uint64(len(prog)); pc++ {
+		if !vr.start[pc] {
+			continue
+		}
+		if err := vr.operand(pc); err != nil {
+			return err
+		}
+	}
+	sort.Slice(vr.entries, func(i, j int) bool {
+		return vr.entries[i] < vr.entries[j]
+	})
+
+	// Subroutine summaries only ever change a bounded number of times
+	// unless a recursive subroutine keeps popping values.
+	for pass := 0; pass <= len(vr.entries)+1; pass++ {
+		if _, err := vr.analyze(0, nil); err != nil {
+			return err
+		}
+		changed := false
+		for _, entry := range vr.entries {
+			c, err := vr.analyze(entry, vr.subs[entry])
+			if err != nil {
+				return err
+			}
+			if c && pass == len(vr.entries)+1 {
+				return errorf(entry, "unbounded command stack "+
+					"underflow")
+			}
+			changed = changed || c
+		}
+		if !changed {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		prog []uint64
+		pc   uint64
+	}{
+		{
+			name: "illegal instruction",
+			prog: []uint64{OP_NOP, OP_INVALID},
+			pc:   1,
+		},
+		{
+			name: "truncated instruction",
+			prog: []uint64{OP_NOP, OP_PUSH},
+			pc:   1,
+		},
+		{
+			name: "jump into instruction",
+			prog: []uint64{OP_PUSH, 1000, OP_JMP, 1},
+			pc:   2,
+		},
+		{
+			name: "branch out of bounds",
+			prog: []uint64{OP_PUSH, 1, OP_BRT, 4},
+			pc:   2,
+		},
+		{
+			name: "push unknown symbol",
+			prog: []uint64{OP_NOP, OP_PUSH, 4242},
+			pc:   1,
+		},
+		{
+			name: "push reserved id",
+			prog: []uint64{OP_PUSH, 5},
+			pc:   0,
+		},
+		{
+			name: "push label",
+			prog: []uint64{OP_PUSH, 1002},
+			pc:   0,
+		},
+		{
+			name: "pop to constant",
+			prog: []uint64{OP_PUSH, 1000, OP_POP, 1006},
+			pc:   2,
+		},
+		{
+			name: "jsr using variable",
+			prog: []uint64{OP_JSR, 1000, OP_EXIT},
+			pc:   0,
+		},
+		{
+			name: "call variable",
+			prog: []uint64{OP_CALL, 1000},
+			pc:   0,
+		},
+		{
+			name: "underflow",
+			prog: []uint64{OP_PUSH, 1000, OP_ADD},
+			pc:   2,
+		},
+		{
+			name: "call underflow",
+			prog: []uint64{OP_PUSH, 1005, OP_CALL, 1011},
+			pc:   2,
+		},
+		{
+			name: "inconsistent branches",
+			prog: []uint64{
+				OP_PUSH, 1, // 0
+				OP_BRT, 7, // 2
+				OP_PUSH, 1000, // 4
+				OP_NOP, // 6
+				OP_NOP, // 7 reached with depth 0 and 1
+			},
+			pc: 7,
+		},
+		{
+			name: "ret without frame",
+			prog: []uint64{OP_NOP, OP_RET},
+			pc:   1,
+		},
+		{
+			name: "local outside frame",
+			prog: []uint64{OP_PUSHL, 0},
+			pc:   0,
+		},
+		{
+			name: "subroutine underflow",
+			prog: []uint64{
+				OP_JSR, 1002, // 0 subroutine pops a value
+				OP_EXIT, // 2
+				OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT,
+				OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT,
+				OP_POP, 2, // 13
+				OP_RET, // 15
+			},
+			pc: 0,
+		},
+		{
+			name: "local out of bounds",
+			prog: []uint64{
+				OP_JSR, 1002, // 0
+				OP_EXIT, // 2
+				OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT,
+				OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT, OP_ABORT,
+				OP_ENTER, 1, // 13
+				OP_PUSHL, 1, // 15
+				OP_RET, // 17
+			},
+			pc: 15,
+		},
+	}
+	for _, v := range tests {
+		i, err := newImage(v.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(i.GetImage())
+		ve, ok := err.(*VerifyError)
+		if !ok {
+			t.Fatalf("%v: expected verify error, got %v", v.name, err)
+		}
+		if ve.PC != v.pc {
+			t.Fatalf("%v: got pc 0x%x, want 0x%x: %v", v.name, ve.PC,
+				v.pc, err)
+		}
+	}
+}
+
+func TestVerifyRecursion(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,  // 0
+		1005,     // 1
+		OP_JSR,   // 2
+		1002,     // 3 lookup label in symbol table
+		OP_POP,   // 4 store result
+		1005,     // 5
+		OP_EXIT,  // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_ENTER, // 13 f(n) = n < 1 ? n : f(n - 1)
+		1,        // 14
+		OP_POPL,  // 15
+		0,        // 16
+		OP_PUSHL, // 17
+		0,        // 18
+		OP_PUSH,  // 19
+		1007,     // 20
+		OP_LT,    // 21
+		OP_BRF,   // 22
+		27,       // 23
+		OP_PUSHL, // 24
+		0,        // 25
+		OP_RET,   // 26
+		OP_PUSHL, // 27
+		0,        // 28
+		OP_PUSH,  // 29
+		1007,     // 30
+		OP_SUB,   // 31
+		OP_JSR,   // 32
+		1002,     // 33
+		OP_RET,   // 34
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vm.sym[1005].Value.(int) != 0 {
+		t.Fatalf("invalid result %v", vm.sym[1005].Value)
+	}
+}
@@ -27,6 +27,11 @@
 // The bottom 256 (SymReserved) opcodes are reserved to express simple things
 // such as TRUE and FALSE.
 //
+// Every JSR creates a frame on the local stack and RET destroys it.
+// A frame contains the local variable slots of a subroutine, ENTER allocates
+// them and PUSHL and POPL access them by slot number.
+// This makes subroutines reentrant and enables recursion.
+//
 // Note that a bunch of documentation isn't visible on godoc.org.
 // They do not enable ?m=all in URLs (include unexported doco).
 // So make sure to reference the source or run godoc locally to see the
@@ -34,12 +39,17 @@
 package vm
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"reflect"
+	"unicode/utf8"
 
+	"github.com/marcopeereboom/gck/diagnostics"
 	"github.com/marcopeereboom/gck/tvm/section"
 	"github.com/marcopeereboom/gck/tvm/stdlib"
 )
@@ -69,7 +79,12 @@ const (
 	OP_CALL    = 19 // stdlib call
 	OP_JMP     = 20 // jump to location
 	OP_RET     = 21 // return from subroutine
-	OP_INVALID = 22 // must be last
+	OP_ENTER   = 22 // allocate local variable slots in current frame
+	OP_PUSHL   = 23 // push local variable onto command stack
+	OP_POPL    = 24 // pop command stack into local variable
+	OP_LEN     = 25 // length of string
+	OP_NOT     = 26 // logical not
+	OP_INVALID = 27 // must be last
 )
 
 const (
@@ -77,13 +92,15 @@ const (
 	VmInvalidStack = iota
 	VmCmdStack
 	VmCallStack
+	VmLocalStack
 )
 
 const (
 	// Constants that define default stack size.
 	// This is denominated in uint64.
-	vmInitialStackSize     = 1024
-	vmInitialCallStackSize = 1024
+	vmInitialStackSize      = 1024
+	vmInitialCallStackSize  = 1024
+	vmInitialLocalStackSize = 1024
 )
 
 // instruction describes a VM instruction and its limits.
@@ -127,6 +144,15 @@ var (
 		{2, 0, VmInvalidStack, "jmp"},
 		{1, 1, VmCallStack, "ret"},
 
+		// operand is a local variable slot or count
+		{2, 0, VmLocalStack, "enter"},
+		{2, 0, VmCmdStack, "pushl"},
+		{2, 1, VmCmdStack, "popl"},
+
+		// require symbol table
+		{1, 1, VmCmdStack, "len"},
+		{1, 1, VmCmdStack, "not"},
+
 		// marks end of opcode list
 		{0, 0, VmInvalidStack, "invalid"},
 	}
@@ -136,7 +162,8 @@ var (
 
 // Vm is the Virtual Machine context
 type Vm struct {
-	sym map[uint64]*section.Symbol // symbol table
+	sym   map[uint64]*section.Symbol // symbol table
+	funcs *stdlib.Registry           // functions callable with OP_CALL
 
 	// stacks
 	sp        int      // stack pointer
@@ -144,6 +171,11 @@ type Vm struct {
 	cs        int      // call stack pointer
 	callStack []uint64 // call stack, contains return addresses
 
+	// frames
+	fp     int      // frame pointer, first local slot of current frame
+	lp     int      // local stack pointer
+	locals []uint64 // local stack, contains saved fp followed by slots
+
 	// gc
 	zero uint64 // current number of 0 ref symbuls
 	gc   uint64 // number of GCs run
@@ -159,10 +191,13 @@ type Vm struct {
 	runTrace     string          // runtime trace
 	paused       bool            // set to tru to pause execution
 	bp           map[uint64]bool // breakpoint
+	debug        *section.Debug  // source lines, nil if not present
 
 	// stats
 	instructions uint64 // number of instructions run
 	tainted      bool   // if set stats are worthless
+
+	limits Limits // resource limits, see SetLimits
 }
 
 // randomUint64 generates a random uint64 value.
@@ -214,19 +249,49 @@ func (v *Vm) GetId() (uint64, error) {
 }
 
 // New creates a new VM context for image.
+// The VM can call the builtin stdlib functions.
 // If the image is invalid the function throws an error.
 func New(image []byte) (*Vm, error) {
+	return NewWithRegistry(image, stdlib.NewRegistry())
+}
+
+// NewWithRegistry creates a new VM context for image that calls the functions
+// in registry r.
+// This is meant for embedding applications that compile source against the
+// same registry.
+// If the image is invalid the function throws an error.
+// The code is verified before it is executed, problems are returned as a
+// VerifyError.
+func NewWithRegistry(image []byte, r *stdlib.Registry) (*Vm, error) {
+	sections, err := section.SectionsFromImage(image)
+	if err != nil {
+		return nil, err
+	}
+	v, err := load(sections, r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verify(v.prog, v.sym)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// load creates a new VM context from the code and symbol table sections of
+// an image.
+func load(sections []*section.Section, r *stdlib.Registry) (*Vm, error) {
 	v := Vm{
+		funcs:     r,
 		stack:     make([]uint64, vmInitialStackSize),
 		callStack: make([]uint64, vmInitialCallStackSize),
+		locals:    make([]uint64, vmInitialLocalStackSize),
 		sym:       make(map[uint64]*section.Symbol),
 		bp:        make(map[uint64]bool),
 	}
 
-	sections, err := section.SectionsFromImage(image)
-	if err != nil {
-		return nil, err
-	}
 	var ok bool
 	for _, s := range sections {
 		if s.Payload == nil {
@@ -301,30 +366,64 @@ func New(image []byte) (*Vm, error) {
 				v.sym[sym.Id] = sym
 			}
 
+		case section.DebugId:
+			v.debug, ok = s.Payload.(*section.Debug)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %T "+
+					"for debug section", s.Payload)
+			}
+
 		default:
 			return nil, fmt.Errorf("invalid section 0x%0x", s.Id)
 		}
 	}
 
-	// cross reference stdlib symbols
-	// XXX we need some sort of reverse lookup from the images
-	//funcs := stdlib.GetFunctionNames()
-	//for _, fn := range funcs {
-	//	id, err := v.GetId()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	sym, err := section.New(id, section.OsId, section.SymLabelId,
-	//		"os."+fn, fn)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	v.sym[sym.Id] = sym
-	//}
-
 	return &v, nil
 }
 
+// RegisterFunc exposes Go function fn to the program as name.
+// The program calls it with OP_CALL through an .OS section entry with a
+// matching signature.
+// Argument and result types must be int, *big.Rat, string, bool or nil which
+// accepts any of those.
+// For example:
+//	v.RegisterFunc("app.double", double,
+//		[]reflect.Type{reflect.TypeOf(0)},
+//		[]reflect.Type{reflect.TypeOf(0)})
+func (v *Vm) RegisterFunc(name string, fn stdlib.Func, argTypes,
+	resultTypes []reflect.Type) error {
+
+	return v.funcs.Register(name, fn, argTypes, resultTypes)
+}
+
+// SetOutput sets the destination of the output of the program, for example
+// of os.print.
+// The output belongs to the registry of the vm, see NewWithRegistry.
+// The default is os.Stdout.
+func (v *Vm) SetOutput(w io.Writer) {
+	v.funcs.SetOutput(w)
+}
+
+// sameSignature returns true if the OS call that is stored in the image
+// matches the registered function.
+func sameSignature(oc section.OsCall, f *stdlib.Function) bool {
+	if len(oc.Variables) != len(f.Args) ||
+		len(oc.Results) != len(f.Results) {
+		return false
+	}
+	for k, v := range oc.Variables {
+		if v.Type != f.Args[k] {
+			return false
+		}
+	}
+	for k, v := range oc.Results {
+		if v.Type != f.Results[k] {
+			return false
+		}
+	}
+	return true
+}
+
 // GC garbage collect symbols that have a reference counter that is less than
 // 1.
 func (v *Vm) GC() {
@@ -388,6 +487,8 @@ func (v *Vm) GetStack(loud bool, which int) string {
 	case VmCallStack:
 		sp = v.cs
 		stack = v.callStack
+	case VmLocalStack:
+		return v.getLocals(loud)
 	default:
 		return "INVALID STACK"
 	}
@@ -399,6 +500,30 @@ func (v *Vm) GetStack(loud bool, which int) string {
 	return s
 }
 
+// getLocals returns the local stack with the frame boundaries marked.
+// Set loud to true for extra verbosity.
+func (v *Vm) getLocals(loud bool) string {
+	// find saved frame pointers by walking the frame chain
+	saved := make(map[int]bool)
+	for fp := v.fp; fp > 0; fp = int(v.locals[fp-1]) {
+		saved[fp-1] = true
+	}
+
+	var s string
+	for i := 0; i < v.lp; i++ {
+		switch {
+		case saved[i]:
+			s += fmt.Sprintf("%016x: frame %016x\n", i, v.locals[i])
+		case v.locals[i] == 0:
+			s += fmt.Sprintf("%016x: unassigned\n", i)
+		default:
+			s += fmt.Sprintf("%016x: %v\n", i,
+				v.demangle(loud, v.locals[i]))
+		}
+	}
+	return s
+}
+
 // GetBreak returns all curent set breakpoints.
 func (v *Vm) GetBreak() string {
 	if len(v.bp) == 0 {
@@ -437,6 +562,10 @@ func (v *Vm) demangle(loud bool, id uint64) string {
 	switch valt := sym.Value.(type) {
 	case uint64:
 		val = fmt.Sprintf("0x%0x", valt)
+	case string:
+		val = fmt.Sprintf("%q", valt)
+	case section.OsCall:
+		val = valt.String()
 	default:
 		val = sym.Value
 	}
@@ -471,7 +600,13 @@ func (v *Vm) disassemble(loud bool, pc uint64, prog []uint64) string {
 		}
 	}
 	for i = 0; i < vmInstructions[ins].size-1; i++ {
-		args += " " + v.demangle(loud, prog[pc+i+1])
+		switch ins {
+		case OP_ENTER, OP_PUSHL, OP_POPL:
+			// not a symbol
+			args += fmt.Sprintf(" %v", prog[pc+i+1])
+		default:
+			args += " " + v.demangle(loud, prog[pc+i+1])
+		}
 	}
 	if loud {
 		todo := 2
@@ -493,6 +628,12 @@ func (v *Vm) vonNeumann() error {
 		v.GC()
 	}
 
+	err := v.exceeded(InstructionLimit, v.limits.Instructions,
+		v.instructions)
+	if err != nil {
+		return err
+	}
+
 	i := v.prog[v.pc]
 
 	// we try to validate as much as possible up front to keep
@@ -529,7 +670,9 @@ func (v *Vm) vonNeumann() error {
 		return ErrExit
 	case OP_NOP:
 	case OP_PUSH:
-		v.push()
+		if err := v.push(); err != nil {
+			return err
+		}
 	case OP_POP:
 		if err := v.pop(); err != nil {
 			return err
@@ -612,6 +755,26 @@ func (v *Vm) vonNeumann() error {
 		}
 		// note that OP_RET sets the pc, so return
 		return nil
+	case OP_ENTER:
+		if err := v.enter(); err != nil {
+			return err
+		}
+	case OP_PUSHL:
+		if err := v.pushl(); err != nil {
+			return err
+		}
+	case OP_POPL:
+		if err := v.popl(); err != nil {
+			return err
+		}
+	case OP_LEN:
+		if err := v.len(); err != nil {
+			return err
+		}
+	case OP_NOT:
+		if err := v.not(); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("illegal instruction 0x%0x at 0x%0x",
 			i, v.pc)
@@ -621,21 +784,86 @@ func (v *Vm) vonNeumann() error {
 	return nil
 }
 
+// runtimeError returns err as a RuntimeError with a backtrace of the failing
+// instruction.
+// ErrExit is not an error and is returned as is, as are limit errors so that
+// callers can tell them apart.
+func (v *Vm) runtimeError(err error) error {
+	if _, ok := err.(*LimitError); ok || err == ErrExit {
+		return err
+	}
+	return &RuntimeError{
+		Err: diagnostics.Errorf(v.pos(v.pc), diagnostics.Runtime,
+			"%v", err),
+		PC:     v.pc,
+		Frames: v.backtrace(),
+	}
+}
+
+// StopError is returned by RunContext when its context is done.
+// Execution can be resumed by calling Run or RunContext again.
+type StopError struct {
+	PC  uint64 // location of the instruction that will be executed next
+	Err error  // error of the context
+}
+
+func (e *StopError) Error() string {
+	return fmt.Sprintf("stopped at 0x%0x: %v", e.PC, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+// safePoint returns true if the context should be checked before the
+// instruction at pc is executed.
+// These are calls and backward jumps which bound the time that a program can
+// run between checks.
+func (v *Vm) safePoint() bool {
+	switch v.prog[v.pc] {
+	case OP_JSR, OP_CALL:
+		return true
+	case OP_JMP, OP_BRT, OP_BRF:
+		return v.pc+1 < uint64(len(v.prog)) && v.prog[v.pc+1] <= v.pc
+	}
+	return false
+}
+
+// Run executes the image until the program ends.
 func (v *Vm) Run() error {
+	return v.RunContext(context.Background())
+}
+
+// RunContext executes the image until the program ends or until ctx is done.
+// The context is checked before calls and backward jumps are executed.
+// When it is done a StopError that contains the program counter and the
+// error of the context is returned and the vm is left in a state in which
+// execution can be resumed.
+func (v *Vm) RunContext(ctx context.Context) error {
 	if len(v.prog) == 0 {
 		return fmt.Errorf("no code section")
 	}
 
+	done := ctx.Done()
 	for v.pc < uint64(len(v.prog)) {
+		if done != nil && v.safePoint() {
+			select {
+			case <-done:
+				return &StopError{PC: v.pc, Err: ctx.Err()}
+			default:
+			}
+		}
+
 		err := v.vonNeumann()
 		if err != nil {
-			return err
+			return v.runtimeError(err)
 		}
 	}
 	return nil
 }
 
-// missing: pause/unpause, step, breakpoints, load/save snapshot, backtrace
+// missing: pause/unpause, step, breakpoints
 
 // Run start executing the image that was provided during New.
 // If the program violates any rules it will be aborted and Run will return
@@ -682,7 +910,7 @@ func (v *Vm) run(c chan vmCommand, r chan vmResponse, interactive bool) {
 
 		err := v.vonNeumann()
 		if err != nil {
-			r <- vmResponse{err: err}
+			r <- vmResponse{err: v.runtimeError(err)}
 			return
 		}
 	}
@@ -709,11 +937,11 @@ func (v *Vm) stackGrow(sp int, oldStack *[]uint64, s string) {
 // This is the slow path and should be avoided if possible.
 func (v *Vm) ref(sym uint64, c int) (int, error) {
 	if sym < section.SymReserved {
-		return -1, fmt.Errorf("symbol reserved: %v", v)
+		return -1, fmt.Errorf("symbol reserved: %v", sym)
 	}
 	s, found := v.sym[sym]
 	if !found {
-		return -1, fmt.Errorf("symbol not found: %v", v)
+		return -1, fmt.Errorf("symbol not found: %016x", sym)
 	}
 
 	rc, err := s.Ref(c)
@@ -724,11 +952,16 @@ func (v *Vm) ref(sym uint64, c int) (int, error) {
 // It pushes a reserved or symbol ID onto the stack.
 // The stack pointer is incremented by exactly one uint64.
 // Push automatically grows the stack if needed.
-func (v *Vm) push() {
+func (v *Vm) push() error {
+	err := v.exceeded(CmdStackLimit, v.limits.CmdStack, uint64(v.sp))
+	if err != nil {
+		return err
+	}
 	v.stackGrow(v.sp, &v.stack, "command")
 	v.ref(v.prog[v.pc+1], 1)
 	v.stack[v.sp] = v.prog[v.pc+1]
 	v.sp++
+	return nil
 }
 
 // push handles the OP_POP opcode.
@@ -743,6 +976,10 @@ func (v *Vm) pop() error {
 
 	// discard value
 	if v.prog[v.pc+1] == section.SymReservedDiscard {
+		// reserved values are not backed by a symbol
+		if v.stack[v.sp-1] < section.SymReserved {
+			return nil
+		}
 		src, ok := v.sym[v.stack[v.sp-1]]
 		if !ok {
 			return fmt.Errorf("discard symbol src not found %016x",
@@ -765,10 +1002,9 @@ func (v *Vm) pop() error {
 	if !ok {
 		return fmt.Errorf("symbol dst not found %016x", v.prog[v.pc+1])
 	}
-	src, ok := v.sym[v.stack[v.sp-1]]
-	if !ok {
-		return fmt.Errorf("symbol src not found %016x",
-			v.sym[v.stack[v.sp-1]])
+	val, src, err := v.value(v.stack[v.sp-1])
+	if err != nil {
+		return err
 	}
 
 	// check pop section
@@ -779,22 +1015,55 @@ func (v *Vm) pop() error {
 	}
 
 	// overwrite value with a copy
-	switch sv := src.Value.(type) {
+	switch sv := val.(type) {
 	case *big.Rat:
 		// make a copy
 		dst.Value = new(big.Rat).Set(sv)
 	default:
 		dst.Value = sv
 	}
-	dst.TypeId = src.TypeId
+	if src == nil {
+		dst.TypeId = section.SymBoolId
+	} else {
+		dst.TypeId = src.TypeId
+	}
 
 	// lower ref counter
-	rc, err := src.Ref(-1)
+	return v.unref(src)
+}
+
+// value returns the value of the symbol ID id.
+// The reserved TRUE and FALSE IDs are not backed by a symbol and are returned
+// as a bool and a nil symbol.
+func (v *Vm) value(id uint64) (interface{}, *section.Symbol, error) {
+	switch id {
+	case section.SymReservedFalse:
+		return false, nil, nil
+	case section.SymReservedTrue:
+		return true, nil, nil
+	}
+
+	s, found := v.sym[id]
+	if !found {
+		return nil, nil, fmt.Errorf("symbol not found 0x%016x", id)
+	}
+	return s.Value, s, nil
+}
+
+// unref lowers the reference counter of symbol s.
+// A nil symbol is a reserved value and is ignored.
+func (v *Vm) unref(s *section.Symbol) error {
+	if s == nil {
+		return nil
+	}
+	rc, err := s.Ref(-1)
+	if err != nil {
+		return err
+	}
 	if rc == 0 {
 		v.zero++
 	}
-	return err
-
+	return nil
 }
 
 // mathOp handles generic math operations.
@@ -825,13 +1094,7 @@ func (v *Vm) mathOp(cb func(int, interface{}, interface{}) (interface{}, error))
 			}
 
 			// create new symbol for stack
-			id, err := v.GetId()
-			if err != nil {
-				return err
-			}
-
-			sym, err = section.New(id, section.VariableId, 1, "",
-				val)
+			sym, err = v.alloc(val)
 			if err != nil {
 				return err
 			}
@@ -850,13 +1113,26 @@ func (v *Vm) mathOp(cb func(int, interface{}, interface{}) (interface{}, error))
 			}
 
 			// create new symbol for stack
-			id, err := v.GetId()
+			sym, err = v.alloc(val)
 			if err != nil {
 				return err
 			}
+		default:
+			return fmt.Errorf("can't %v %T to %T",
+				vmInstructions[v.prog[v.pc]].name, t, t1)
+		}
 
-			sym, err = section.New(id, section.VariableId, 1, "",
-				val)
+	// STRINGS
+	case string:
+		switch t1 := s1.Value.(type) {
+		case string:
+			val, err := cb(section.SymStringId, t, t1)
+			if err != nil {
+				return err
+			}
+
+			// create new symbol for stack
+			sym, err = v.alloc(val)
 			if err != nil {
 				return err
 			}
@@ -869,9 +1145,6 @@ func (v *Vm) mathOp(cb func(int, interface{}, interface{}) (interface{}, error))
 			vmInstructions[v.prog[v.pc]].name, t)
 	}
 
-	// insert new symbol
-	v.sym[sym.Id] = sym
-
 	// adjust ref counters
 	rc, err := s0.Ref(-1)
 	if err != nil {
@@ -905,6 +1178,7 @@ func (v *Vm) mathOp(cb func(int, interface{}, interface{}) (interface{}, error))
 // The symbol ID resides on top of the stack.
 // The symbol IDs for x and y are no longer on the stack.
 // The stack pointer is decremented by exactly one uint64.
+// Adding two strings results in their concatenation.
 func (v *Vm) add() error {
 	return v.mathOp(func(mode int, t, t1 interface{}) (interface{}, error) {
 		switch mode {
@@ -912,6 +1186,8 @@ func (v *Vm) add() error {
 			return t.(int) + t1.(int), nil
 		case section.SymNumId:
 			return new(big.Rat).Add(t.(*big.Rat), t1.(*big.Rat)), nil
+		case section.SymStringId:
+			return t.(string) + t1.(string), nil
 		}
 		return nil, fmt.Errorf("invalid add mode %v", mode)
 	})
@@ -981,7 +1257,7 @@ func (v *Vm) div() error {
 			if t1.(int) == 0 {
 				return nil, fmt.Errorf("divide by 0")
 			}
-			return t.(int) * t1.(int), nil
+			return t.(int) / t1.(int), nil
 		case section.SymNumId:
 			if t1.(*big.Rat).Sign() == 0 {
 				return nil, fmt.Errorf("divide by 0")
@@ -1016,11 +1292,8 @@ func (v *Vm) neg() error {
 		val := new(big.Rat).Neg(t)
 
 		// create new symbol for stack
-		id, err := v.GetId()
-		if err != nil {
-			return err
-		}
-		sym, err = section.New(id, section.VariableId, 1, "", val)
+		var err error
+		sym, err = v.alloc(val)
 		if err != nil {
 			return err
 		}
@@ -1029,11 +1302,8 @@ func (v *Vm) neg() error {
 		val := -t
 
 		// create new symbol for stack
-		id, err := v.GetId()
-		if err != nil {
-			return err
-		}
-		sym, err = section.New(id, section.VariableId, 1, "", val)
+		var err error
+		sym, err = v.alloc(val)
 		if err != nil {
 			return err
 		}
@@ -1042,9 +1312,6 @@ func (v *Vm) neg() error {
 			vmInstructions[v.prog[v.pc]].name, t)
 	}
 
-	// insert new symbol
-	v.sym[sym.Id] = sym
-
 	// adjust ref counter of source
 	rc, err := s.Ref(-1)
 	if err != nil {
@@ -1061,24 +1328,26 @@ func (v *Vm) neg() error {
 }
 
 // cmpOp is the generic comparison operation.
+// Strings are compared lexically byte-wise.
+// Booleans can only be compared for (in)equality.
 // See individual opcodes for more information.
 func (v *Vm) cmpOp(cb func(int, interface{}, interface{}) (bool, error)) error {
 
 	var rv bool
 
-	s0, found := v.sym[v.stack[v.sp-2]]
-	if !found {
-		return fmt.Errorf("symbol not found 0x%016x", v.stack[v.sp-2])
+	v0, s0, err := v.value(v.stack[v.sp-2])
+	if err != nil {
+		return err
 	}
-	s1, found := v.sym[v.stack[v.sp-1]]
-	if !found {
-		return fmt.Errorf("symbol not found 0x%016x", v.stack[v.sp-1])
+	v1, s1, err := v.value(v.stack[v.sp-1])
+	if err != nil {
+		return err
 	}
 
 	// assert same types
-	switch t := s0.Value.(type) {
+	switch t := v0.(type) {
 	case *big.Rat:
-		switch t1 := s1.Value.(type) {
+		switch t1 := v1.(type) {
 		case *big.Rat:
 			var errOp error
 			rv, errOp = cb(section.SymNumId, t, t1)
@@ -1091,7 +1360,7 @@ func (v *Vm) cmpOp(cb func(int, interface{}, interface{}) (bool, error)) error {
 		}
 
 	case int:
-		switch t1 := s1.Value.(type) {
+		switch t1 := v1.(type) {
 		case int:
 			var errOp error
 			rv, errOp = cb(section.SymIntId, t, t1)
@@ -1103,26 +1372,44 @@ func (v *Vm) cmpOp(cb func(int, interface{}, interface{}) (bool, error)) error {
 				vmInstructions[v.prog[v.pc]].name, t, t1)
 		}
 
+	case string:
+		switch t1 := v1.(type) {
+		case string:
+			var errOp error
+			rv, errOp = cb(section.SymStringId, t, t1)
+			if errOp != nil {
+				return errOp
+			}
+		default:
+			return fmt.Errorf("can't %v %T to %T",
+				vmInstructions[v.prog[v.pc]].name, t, t1)
+		}
+
+	case bool:
+		switch t1 := v1.(type) {
+		case bool:
+			var errOp error
+			rv, errOp = cb(section.SymBoolId, t, t1)
+			if errOp != nil {
+				return errOp
+			}
+		default:
+			return fmt.Errorf("can't %v %T to %T",
+				vmInstructions[v.prog[v.pc]].name, t, t1)
+		}
+
 	default:
 		return fmt.Errorf("%v does not support type: %T",
 			vmInstructions[v.prog[v.pc]].name, t)
 	}
 
 	// adjust ref counters
-	rc, err := s0.Ref(-1)
-	if err != nil {
+	if err := v.unref(s0); err != nil {
 		return err
 	}
-	if rc == 0 {
-		v.zero++
-	}
-	rc, err = s1.Ref(-1)
-	if err != nil {
+	if err := v.unref(s1); err != nil {
 		return err
 	}
-	if rc == 0 {
-		v.zero++
-	}
 
 	v.sp--
 	if rv {
@@ -1151,6 +1438,10 @@ func (v *Vm) eq() error {
 			return 0 == t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) == t1.(int), nil
+		case section.SymStringId:
+			return t.(string) == t1.(string), nil
+		case section.SymBoolId:
+			return t.(bool) == t1.(bool), nil
 		}
 		return false, fmt.Errorf("invalid == mode %v", mode)
 	})
@@ -1173,6 +1464,10 @@ func (v *Vm) neq() error {
 			return 0 != t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) != t1.(int), nil
+		case section.SymStringId:
+			return t.(string) != t1.(string), nil
+		case section.SymBoolId:
+			return t.(bool) != t1.(bool), nil
 		}
 		return false, fmt.Errorf("invalid != mode %v", mode)
 	})
@@ -1195,6 +1490,8 @@ func (v *Vm) lt() error {
 			return -1 == t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) < t1.(int), nil
+		case section.SymStringId:
+			return t.(string) < t1.(string), nil
 		}
 		return false, fmt.Errorf("invalid < mode %v", mode)
 	})
@@ -1217,6 +1514,8 @@ func (v *Vm) gt() error {
 			return 1 == t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) > t1.(int), nil
+		case section.SymStringId:
+			return t.(string) > t1.(string), nil
 		}
 		return false, fmt.Errorf("invalid > mode %v", mode)
 	})
@@ -1239,6 +1538,8 @@ func (v *Vm) le() error {
 			return 0 >= t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) <= t1.(int), nil
+		case section.SymStringId:
+			return t.(string) <= t1.(string), nil
 		}
 		return false, fmt.Errorf("invalid <= mode %v", mode)
 	})
@@ -1261,11 +1562,78 @@ func (v *Vm) ge() error {
 			return 0 <= t.(*big.Rat).Cmp(t1.(*big.Rat)), nil
 		case section.SymIntId:
 			return t.(int) >= t1.(int), nil
+		case section.SymStringId:
+			return t.(string) >= t1.(string), nil
 		}
 		return false, fmt.Errorf("invalid >= mode %v", mode)
 	})
 }
 
+// not handles the OP_NOT opcode.
+// It replaces the boolean on top of the stack with its negation.
+// For example:
+//	push TRUE
+//	not
+// Results in FALSE which is stored as 0x0 on the stack.
+// The stack pointer is unaltered.
+func (v *Vm) not() error {
+	rv, err := v.test(v.stack[v.sp-1])
+	if err != nil {
+		return err
+	}
+
+	if rv {
+		v.stack[v.sp-1] = section.SymReservedFalse
+	} else {
+		v.stack[v.sp-1] = section.SymReservedTrue
+	}
+
+	return nil
+}
+
+// len handles the OP_LEN opcode.
+// It replaces the string on top of the stack with its length.
+// The length is counted in characters (runes), not bytes.
+// For example:
+//	push x ("héllo")
+//	len
+// Results in 5 which is stored in a symbol.
+// The symbol ID resides on top of the stack.
+// The symbol ID for x is no longer on the stack.
+// The stack pointer is unaltered.
+func (v *Vm) len() error {
+	s, found := v.sym[v.stack[v.sp-1]]
+	if !found {
+		return fmt.Errorf("symbol not found 0x%016x", v.stack[v.sp-1])
+	}
+
+	str, ok := s.Value.(string)
+	if !ok {
+		return fmt.Errorf("%v does not support type: %T",
+			vmInstructions[v.prog[v.pc]].name, s.Value)
+	}
+
+	// create new symbol for stack
+	sym, err := v.alloc(utf8.RuneCountInString(str))
+	if err != nil {
+		return err
+	}
+
+	// adjust ref counter of source
+	rc, err := s.Ref(-1)
+	if err != nil {
+		return err
+	}
+	if rc == 0 {
+		v.zero++
+	}
+
+	// replace stack value
+	v.stack[v.sp-1] = sym.Id
+
+	return nil
+}
+
 // jmp handles the OP_JMP opcode.
 // It jumps to the location that is the opcode argument.
 // Jump can only do direct jumps within the code segment.
@@ -1287,7 +1655,18 @@ func (v *Vm) jmp() error {
 
 // call handles the OP_CALL opcode.
 // All calls are essentially equivalent to OS standard library calls.
-// TODO this is currently not fully implemented.
+// The function is looked up in the registry of the VM and its signature must
+// match the one that is stored in the image.
+// The arguments are popped off the command stack according to the OsCall
+// signature of the symbol ID that is its argument.
+// The last argument is on top of the stack.
+// The results returned by the stdlib function are pushed in order.
+// For example (assume 0x1234 is os.print(v any)()):
+//	push	x
+//	call	0x1234
+// The stack pointer is decremented by the number of arguments and incremented
+// by the number of results.
+// An error returned by the stdlib function aborts execution.
 func (v *Vm) call() error {
 	// lookup label in symbol table
 	s, found := v.sym[v.prog[v.pc+1]]
@@ -1304,20 +1683,102 @@ func (v *Vm) call() error {
 		return fmt.Errorf("call can not jump to type %v",
 			section.Symbols[s.TypeId])
 	}
+	oc, ok := s.Value.(section.OsCall)
+	if !ok {
+		return fmt.Errorf("call invalid os symbol %v", s.Name)
+	}
+	f, found := v.funcs.Lookup(oc.Name)
+	if !found {
+		return fmt.Errorf("call %v not registered", oc.Name)
+	}
+	if !sameSignature(oc, f) {
+		return fmt.Errorf("call %v signature mismatch: %v", oc.Name,
+			oc.String())
+	}
+
+	// collect arguments
+	n := len(oc.Variables)
+	if v.sp < n {
+		return fmt.Errorf("call %v stack underflow", oc.Name)
+	}
+	args := make([]interface{}, 0, n)
+	syms := make([]*section.Symbol, 0, n)
+	for i, in := range oc.Variables {
+		val, sym, err := v.value(v.stack[v.sp-n+i])
+		if err != nil {
+			return err
+		}
+		if in.Type != nil && reflect.TypeOf(val) != in.Type {
+			return fmt.Errorf("call %v argument %v: can't use %T "+
+				"as %v", oc.Name, i, val, in.Type)
+		}
+		// the stdlib must not modify symbols
+		if r, ok := val.(*big.Rat); ok {
+			val = new(big.Rat).Set(r)
+		}
+		args = append(args, val)
+		syms = append(syms, sym)
+	}
 
-	// XXX handle args and return values
-	rv, err := stdlib.Dispatch(s.Name)
+	rv, err := f.Fn(args...)
 	if err != nil {
 		return err
 	}
+	if rv == nil {
+		// functions without results may return nothing
+		rv = &stdlib.Result{}
+	}
+	if rv.Error != nil {
+		return fmt.Errorf("%v: %v", oc.Name, rv.Error)
+	}
+	if len(rv.Rv) != len(oc.Results) {
+		return fmt.Errorf("call %v returned %v results, expected %v",
+			oc.Name, len(rv.Rv), len(oc.Results))
+	}
 
-	// push sucess/failure on the stack
-	if rv.Error == nil {
-		v.stack[v.sp] = section.SymReservedTrue
-	} else {
-		v.stack[v.sp] = section.SymReservedFalse
+	// release arguments
+	for _, sym := range syms {
+		if err := v.unref(sym); err != nil {
+			return err
+		}
+	}
+	v.sp -= n
+
+	// push results
+	for i, r := range rv.Rv {
+		out := oc.Results[i]
+		if out.Type != nil && reflect.TypeOf(r) != out.Type {
+			return fmt.Errorf("call %v result %v: can't use %T "+
+				"as %v", oc.Name, i, r, out.Type)
+		}
+
+		err := v.exceeded(CmdStackLimit, v.limits.CmdStack,
+			uint64(v.sp))
+		if err != nil {
+			return err
+		}
+		v.stackGrow(v.sp, &v.stack, "command")
+		switch rr := r.(type) {
+		case bool:
+			// booleans are reserved symbols
+			if rr {
+				v.stack[v.sp] = section.SymReservedTrue
+			} else {
+				v.stack[v.sp] = section.SymReservedFalse
+			}
+		default:
+			sym, err := v.alloc(r)
+			if le, ok := err.(*LimitError); ok {
+				return le
+			}
+			if err != nil {
+				return fmt.Errorf("call %v result %v: %v",
+					oc.Name, i, err)
+			}
+			v.stack[v.sp] = sym.Id
+		}
+		v.sp++
 	}
-	v.sp++
 
 	return nil
 }
@@ -1339,6 +1800,27 @@ func (v *Vm) call() error {
 // The call stack pointer is incremented by one and contains the return address
 // that ret will jump to.
 // In this example it would return to 0x02.
+//
+// Arguments and results are passed on the command stack.
+// The caller pushes the arguments in order and the subroutine pops them in
+// reverse order.
+// Prior to ret the subroutine pushes its results in order, leaving them on
+// top of the command stack for the caller.
+// For example z = add(x, y):
+//	push	x
+//	push	y
+//	jsr	add
+//	pop	z
+//	..
+//	add:
+//	pop	b
+//	pop	a
+//	push	a
+//	push	b
+//	add
+//	pop	r
+//	push	r
+//	ret
 func (v *Vm) jsr() error {
 	// lookup label in symbol table
 	s, found := v.sym[v.prog[v.pc+1]]
@@ -1372,10 +1854,20 @@ func (v *Vm) jsr() error {
 	if ret >= uint64(len(v.prog)) {
 		return fmt.Errorf("jsr return value out of bounds")
 	}
+	err := v.exceeded(CallStackLimit, v.limits.CallStack, uint64(v.cs))
+	if err != nil {
+		return err
+	}
 	v.stackGrow(v.cs, &v.callStack, "call")
 	v.callStack[v.cs] = ret
 	v.cs++
 
+	// create new frame
+	v.stackGrow(v.lp, &v.locals, "local")
+	v.locals[v.lp] = uint64(v.fp)
+	v.lp++
+	v.fp = v.lp
+
 	v.pc = location
 	return nil
 }
@@ -1397,21 +1889,36 @@ func (v *Vm) jsr() error {
 // In this example the brt call would jump over the nop at 0x04.
 func (v *Vm) brt() error {
 	v.sp--
-	rv := v.stack[v.sp]
-	switch rv {
-	case section.SymReservedFalse:
+	rv, err := v.test(v.stack[v.sp])
+	if err != nil {
+		return err
+	}
+	if !rv {
 		v.pc += 2
 		return nil
-	case section.SymReservedTrue:
-		location := v.prog[v.pc+1]
-		if location >= uint64(len(v.prog)) {
-			return fmt.Errorf("brt out of bounds")
-		}
-		v.pc = location
-		return nil
 	}
+	location := v.prog[v.pc+1]
+	if location >= uint64(len(v.prog)) {
+		return fmt.Errorf("brt out of bounds")
+	}
+	v.pc = location
+	return nil
+}
 
-	return fmt.Errorf("brt not testing true/false")
+// test returns the boolean value of the symbol ID id that was popped off the
+// stack.
+// It is either a reserved TRUE/FALSE or a bool symbol.
+func (v *Vm) test(id uint64) (bool, error) {
+	val, s, err := v.value(id)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v not testing true/false",
+			vmInstructions[v.prog[v.pc]].name)
+	}
+	return b, v.unref(s)
 }
 
 // brf handles the OP_BRF opcode.
@@ -1431,21 +1938,20 @@ func (v *Vm) brt() error {
 // In this example the brf call would jump over the nop at 0x04.
 func (v *Vm) brf() error {
 	v.sp--
-	rv := v.stack[v.sp]
-	switch rv {
-	case section.SymReservedFalse:
-		location := v.prog[v.pc+1]
-		if location >= uint64(len(v.prog)) {
-			return fmt.Errorf("brf out of bounds")
-		}
-		v.pc = location
-		return nil
-	case section.SymReservedTrue:
+	rv, err := v.test(v.stack[v.sp])
+	if err != nil {
+		return err
+	}
+	if rv {
 		v.pc += 2
 		return nil
 	}
-
-	return fmt.Errorf("brf not testing true/false")
+	location := v.prog[v.pc+1]
+	if location >= uint64(len(v.prog)) {
+		return fmt.Errorf("brf out of bounds")
+	}
+	v.pc = location
+	return nil
 }
 
 // ret handles the OP_RET opcode.
@@ -1464,12 +1970,152 @@ func (v *Vm) brf() error {
 // The call stack pointer is incremented by one and contains the return address
 // that ret will jump to.
 // In this example it would return to 0x02.
+// Results of the subroutine are left on the command stack, see jsr.
+// The frame that was created by jsr is destroyed and the local variables it
+// contains are released.
 func (v *Vm) ret() error {
+	if v.fp == 0 {
+		return fmt.Errorf("ret without frame")
+	}
+
 	v.cs--
 	ret := v.callStack[v.cs]
 	if ret >= uint64(len(v.prog)) {
 		return fmt.Errorf("ret return value out of bounds")
 	}
+
+	// release locals
+	for i := v.fp; i < v.lp; i++ {
+		if v.locals[i] == 0 {
+			continue
+		}
+		rc, err := v.ref(v.locals[i], -1)
+		if err != nil {
+			return err
+		}
+		if rc == 0 {
+			v.zero++
+		}
+	}
+
+	// restore previous frame
+	v.lp = v.fp - 1
+	v.fp = int(v.locals[v.lp])
+
 	v.pc = ret
 	return nil
 }
+
+// enter handles the OP_ENTER opcode.
+// It allocates the number of local variable slots that is its argument in the
+// current frame.
+// The slots are unassigned until they are written by popl.
+// Typically enter is the first instruction of a subroutine.
+// For example:
+//	0x80	enter	2
+//	0x81	2	number of slots
+// The command stack pointer is unchanged.
+// The local stack pointer is incremented by the number of slots.
+func (v *Vm) enter() error {
+	if v.fp == 0 {
+		return fmt.Errorf("enter outside of subroutine")
+	}
+
+	n := v.prog[v.pc+1]
+	if n > uint64(len(v.prog)) {
+		// can't possibly address that many
+		return fmt.Errorf("enter too many locals %v", n)
+	}
+	for i := uint64(0); i < n; i++ {
+		v.stackGrow(v.lp, &v.locals, "local")
+		v.locals[v.lp] = 0
+		v.lp++
+	}
+
+	return nil
+}
+
+// local returns the local stack index of the slot that is the opcode argument.
+func (v *Vm) local() (int, error) {
+	slot := v.prog[v.pc+1]
+	if v.fp == 0 || slot >= uint64(v.lp-v.fp) {
+		return 0, fmt.Errorf("local %v out of bounds", slot)
+	}
+	return v.fp + int(slot), nil
+}
+
+// pushl handles the OP_PUSHL opcode.
+// It pushes the symbol ID that is stored in the local variable slot onto the
+// stack.
+// Pushing an unassigned slot aborts execution.
+// For example:
+//	pushl	0
+//	pushl	1
+//	add
+// The stack pointer is incremented by exactly one uint64.
+func (v *Vm) pushl() error {
+	i, err := v.local()
+	if err != nil {
+		return err
+	}
+	if v.locals[i] == 0 {
+		return fmt.Errorf("local %v used before assignment", i-v.fp)
+	}
+
+	err = v.exceeded(CmdStackLimit, v.limits.CmdStack, uint64(v.sp))
+	if err != nil {
+		return err
+	}
+	v.stackGrow(v.sp, &v.stack, "command")
+	v.ref(v.locals[i], 1)
+	v.stack[v.sp] = v.locals[i]
+	v.sp++
+
+	return nil
+}
+
+// popl handles the OP_POPL opcode.
+// It pops a symbol ID from the stack into a local variable slot.
+// The first pop into a slot creates a new symbol that is owned by the frame;
+// subsequent pops overwrite its value with a copy.
+// The stack pointer is decremented by exactly one uint64.
+func (v *Vm) popl() error {
+	i, err := v.local()
+	if err != nil {
+		return err
+	}
+
+	val, src, err := v.value(v.stack[v.sp-1])
+	if err != nil {
+		return err
+	}
+
+	// copy value
+	if sv, ok := val.(*big.Rat); ok {
+		val = new(big.Rat).Set(sv)
+	}
+
+	if v.locals[i] == 0 {
+		dst, err := v.alloc(val)
+		if err != nil {
+			return err
+		}
+		v.locals[i] = dst.Id
+	} else {
+		dst, ok := v.sym[v.locals[i]]
+		if !ok {
+			return fmt.Errorf("symbol dst not found %016x",
+				v.locals[i])
+		}
+		dst.Value = val
+		if src == nil {
+			dst.TypeId = section.SymBoolId
+		} else {
+			dst.TypeId = src.TypeId
+		}
+	}
+
+	// lower ref counter
+	v.sp--
+	return v.unref(src)
+}
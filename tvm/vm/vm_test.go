@@ -1,12 +1,19 @@
 package vm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/marcopeereboom/gck/tvm/section"
+	"github.com/marcopeereboom/gck/tvm/stdlib"
 )
 
 var (
@@ -37,7 +44,7 @@ func newImage(prog []uint64) (*section.Image, error) {
 	}
 
 	// constants everyone uses
-	c1, err := section.NewConst(1002, "myjsr", uint64(12))
+	c1, err := section.NewConst(1002, "myjsr", uint64(13))
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +60,16 @@ func newImage(prog []uint64) (*section.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	cos, err := section.NewConstSection([]*section.Const{c1, c2, c3, c4})
+	c5, err := section.NewConst(1009, "hello", "hello")
+	if err != nil {
+		return nil, err
+	}
+	c6, err := section.NewConst(1010, "world", "wörld")
+	if err != nil {
+		return nil, err
+	}
+	cos, err := section.NewConstSection([]*section.Const{c1, c2, c3, c4,
+		c5, c6})
 	if err != nil {
 		return nil, err
 	}
@@ -63,23 +79,42 @@ func newImage(prog []uint64) (*section.Image, error) {
 		Id:        1003,
 		Name:      "os.true",
 		Variables: nil,
-		Results:   nil,
+		Results: []section.OsInOut{
+			{Name: "b", Type: reflect.TypeOf(false)},
+		},
 	}
 	o1, err := section.NewOs(1003, "os.true", ov1)
 	if err != nil {
 		return nil, err
 	}
 	ov2 := section.OsCall{
-		Id:        1004,
-		Name:      "os.print",
-		Variables: nil,
-		Results:   nil,
+		Id:   1004,
+		Name: "os.print",
+		Variables: []section.OsInOut{
+			{Name: "v"},
+		},
+		Results: nil,
 	}
 	o2, err := section.NewOs(1004, "os.print", ov2)
 	if err != nil {
 		return nil, err
 	}
-	oss, err := section.NewOsSection([]*section.Os{o1, o2})
+	ov3 := section.OsCall{
+		Id:   1011,
+		Name: "test.sum",
+		Variables: []section.OsInOut{
+			{Name: "a", Type: reflect.TypeOf(0)},
+			{Name: "b", Type: reflect.TypeOf(0)},
+		},
+		Results: []section.OsInOut{
+			{Name: "c", Type: reflect.TypeOf(0)},
+		},
+	}
+	o3, err := section.NewOs(1011, "test.sum", ov3)
+	if err != nil {
+		return nil, err
+	}
+	oss, err := section.NewOsSection([]*section.Os{o1, o2, o3})
 	if err != nil {
 		return nil, err
 	}
@@ -106,33 +141,51 @@ func newImage(prog []uint64) (*section.Image, error) {
 	return i, nil
 }
 
+// sum is a host function that is registered by run.
+func sum(args ...interface{}) (*stdlib.Result, error) {
+	return &stdlib.Result{Rv: []interface{}{args[0].(int) + args[1].(int)}},
+		nil
+}
+
 func execute(prog []uint64, t *testing.T) error {
+	_, err := run(prog, t)
+	return err
+}
+
+// run executes prog and returns the vm for inspection.
+func run(prog []uint64, t *testing.T) (*Vm, error) {
 	i, err := newImage(prog)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// store a copy of the image
 	f, err := ioutil.TempFile(os.TempDir(), "tvm")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	f.Close()
 	err = ioutil.WriteFile(f.Name(), i.GetImage(), 0660)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	t.Logf("wrote image to file: %v", f.Name())
 
 	// run
 	vm, err := New(i.GetImage())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	intType := reflect.TypeOf(0)
+	err = vm.RegisterFunc("test.sum", sum,
+		[]reflect.Type{intType, intType}, []reflect.Type{intType})
+	if err != nil {
+		return nil, err
 	}
 	vm.Trace(trace)
 	err = vm.Run()
-	if err != nil {
-		return err
+	if err != nil && err != ErrExit {
+		return nil, err
 	}
 
 	if gc {
@@ -146,7 +199,7 @@ func execute(prog []uint64, t *testing.T) error {
 		t.Logf("=== symbols    ===\n%v", vm.GetSymbols(true))
 	}
 
-	return nil
+	return vm, nil
 }
 
 func TestSubr(t *testing.T) {
@@ -174,6 +227,139 @@ func TestSubr(t *testing.T) {
 	}
 }
 
+func TestSubrArgs(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,  // 0
+		1000,     // 1 first argument
+		OP_PUSH,  // 2
+		1001,     // 3 second argument
+		OP_JSR,   // 4
+		1002,     // 5 lookup label in symbol table
+		OP_POP,   // 6 store result
+		1001,     // 7
+		OP_EXIT,  // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_ADD,   // 13 consume arguments and leave result
+		OP_RET,   // 14
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vm.sp != 0 {
+		t.Errorf("command stack not empty: %v", vm.sp)
+		return
+	}
+	y := vm.sym[1001].Value.(*big.Rat)
+	if y.Cmp(big.NewRat(5, 1)) != 0 {
+		t.Errorf("invalid result %v", y)
+		return
+	}
+}
+
+func TestFrame(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,  // 0
+		1000,     // 1 argument
+		OP_JSR,   // 2
+		1002,     // 3 lookup label in symbol table
+		OP_POP,   // 4 store result
+		1001,     // 5
+		OP_EXIT,  // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_ENTER, // 13 subroutine with 2 locals
+		2,        // 14
+		OP_POPL,  // 15 store argument
+		0,        // 16
+		OP_PUSHL, // 17
+		0,        // 18
+		OP_PUSHL, // 19
+		0,        // 20
+		OP_MUL,   // 21
+		OP_POPL,  // 22
+		1,        // 23
+		OP_PUSHL, // 24 return result
+		1,        // 25
+		OP_RET,   // 26
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vm.fp != 0 || vm.lp != 0 {
+		t.Errorf("frame not released fp %v lp %v", vm.fp, vm.lp)
+		return
+	}
+	y := vm.sym[1001].Value.(*big.Rat)
+	if y.Cmp(big.NewRat(4, 1)) != 0 {
+		t.Errorf("invalid result %v", y)
+		return
+	}
+	// only the image symbols survive gc
+	for _, s := range vm.sym {
+		if s.Name == fmt.Sprintf("%016x", s.Id) {
+			t.Errorf("local leaked %v", s.Id)
+			return
+		}
+	}
+}
+
+func TestFrameUnassigned(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_JSR,   // 0
+		1002,     // 1 lookup label in symbol table
+		OP_EXIT,  // 2
+		OP_ABORT, // 3
+		OP_ABORT, // 4
+		OP_ABORT, // 5
+		OP_ABORT, // 6
+		OP_ABORT, // 7
+		OP_ABORT, // 8
+		OP_ABORT, // 9
+		OP_ABORT, // 10
+		OP_ABORT, // 11
+		OP_ABORT, // 12
+		OP_ENTER, // 13
+		1,        // 14
+		OP_PUSHL, // 15 read unassigned local
+		0,        // 16
+		OP_RET,   // 17
+	}
+
+	err := execute(prog, t)
+	if err == nil {
+		t.Error("expected used before assignment")
+		return
+	}
+}
+
+func TestLocalOutsideFrame(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1000,
+		OP_POPL,
+		0,
+	}
+
+	err := execute(prog, t)
+	if err == nil {
+		t.Error("expected out of bounds")
+		return
+	}
+}
+
 func TestNop(t *testing.T) {
 	var prog []uint64 = []uint64{
 		OP_NOP,
@@ -206,23 +392,24 @@ func TestPush0(t *testing.T) {
 }
 
 func TestPush1(t *testing.T) {
+	// reserved ids other than TRUE and FALSE are rejected by the verifier
 	var prog []uint64 = []uint64{
 		OP_PUSH,
 		0,
 		OP_PUSH,
 		1,
 		OP_PUSH,
-		2,
+		1000,
 		OP_PUSH,
-		3,
+		1001,
 		OP_PUSH,
-		4,
+		1005,
 		OP_PUSH,
-		5,
+		1006,
 		OP_PUSH,
-		6,
+		1009,
 		OP_PUSH,
-		7,
+		1010,
 	}
 
 	err := execute(prog, t)
@@ -366,11 +553,15 @@ func TestOs(t *testing.T) {
 		1003,
 	}
 
-	err := execute(prog, t)
+	vm, err := run(prog, t)
 	if err != nil {
 		t.Error(err)
 		return
 	}
+	if vm.sp != 1 || vm.stack[0] != section.SymReservedTrue {
+		t.Errorf("invalid os.true result")
+		return
+	}
 }
 
 func TestPopFail(t *testing.T) {
@@ -412,17 +603,127 @@ func TestPrint(t *testing.T) {
 	var prog []uint64 = []uint64{
 		OP_PUSH,
 		1000,
-		OP_PUSH,
-		1005, // 1 argument, use a const
 		OP_CALL,
 		1004,
 	}
 
-	err := execute(prog, t)
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every vm writes to its own output
+	var b [2]bytes.Buffer
+	for k := range b {
+		vm, err := New(i.GetImage())
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm.SetOutput(&b[k])
+		err = vm.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vm.sp != 0 {
+			t.Fatalf("arguments not popped")
+		}
+	}
+	for k := range b {
+		if b[k].String() != "2\n" {
+			t.Fatalf("invalid output %v %q", k, b[k].String())
+		}
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1005,
+		OP_PUSH,
+		1008,
+		OP_CALL,
+		1011,
+		OP_POP,
+		1005,
+	}
+
+	vm, err := run(prog, t)
 	if err != nil {
 		t.Error(err)
 		return
 	}
+	if vm.sym[1005].Value.(int) != 6 {
+		t.Errorf("invalid sum %v", vm.sym[1005].Value)
+		return
+	}
+}
+
+func TestRegisterFuncIllegal(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1005,
+		OP_PUSH,
+		1000, // number instead of int
+		OP_CALL,
+		1011,
+	}
+
+	err := execute(prog, t)
+	if err == nil {
+		t.Error("expected type mismatch")
+		return
+	}
+}
+
+func TestRegisterFuncNilResult(t *testing.T) {
+	nop := func(args ...interface{}) (*stdlib.Result, error) {
+		return nil, nil
+	}
+	intType := reflect.TypeOf(0)
+
+	// nil is no results, which is an error if results are expected
+	for _, results := range [][]reflect.Type{nil, {intType}} {
+		oc := section.OsCall{Id: 1000, Name: "test.nop"}
+		for _, v := range results {
+			oc.Results = append(oc.Results,
+				section.OsInOut{Name: "r", Type: v})
+		}
+		o, err := section.NewOs(1000, "test.nop", oc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oss, err := section.NewOsSection([]*section.Os{o})
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := section.NewImage()
+		err = i.AddSection(section.NewCodeSection([]uint64{
+			OP_CALL,
+			1000,
+		}), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = i.AddSection(oss, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := stdlib.NewRegistry()
+		err = r.Register("test.nop", nop, nil, results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm, err := NewWithRegistry(i.GetImage(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Run()
+		if (err != nil) != (len(results) != 0) {
+			t.Fatalf("%v results: unexpected error %v",
+				len(results), err)
+		}
+	}
 }
 
 func TestLoop(t *testing.T) {
@@ -457,3 +758,222 @@ func TestLoop(t *testing.T) {
 		return
 	}
 }
+
+func TestString(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1009,
+		OP_PUSH,
+		1010,
+		OP_ADD, // concatenate
+		OP_LEN,
+		OP_POP,
+		1005,
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vm.sym[1005].Value.(int) != 10 {
+		t.Errorf("invalid length %v", vm.sym[1005].Value)
+		return
+	}
+}
+
+func TestStringCompare(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,  // 0
+		1009,     // 1
+		OP_PUSH,  // 2
+		1010,     // 3
+		OP_LT,    // 4
+		OP_BRT,   // 5
+		8,        // 6 branch over abort
+		OP_ABORT, // 7
+		OP_PUSH,  // 8
+		1009,     // 9
+		OP_PUSH,  // 10
+		1009,     // 11
+		OP_EQ,    // 12
+		OP_BRT,   // 13
+		16,       // 14 branch over abort
+		OP_ABORT, // 15
+		OP_NOP,   // 16
+	}
+
+	err := execute(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestStringIllegal(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1009,
+		OP_PUSH,
+		1005,
+		OP_ADD, // string + int
+	}
+
+	err := execute(prog, t)
+	if err == nil {
+		t.Error("expected type mismatch")
+		return
+	}
+}
+
+func TestBool(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH, // 0
+		section.SymReservedTrue,
+		OP_NOT, // 2
+		OP_POP, // 3, i = false
+		1005,
+		OP_PUSH, // 5
+		1005,
+		OP_PUSH, // 7
+		section.SymReservedFalse,
+		OP_EQ,  // 9
+		OP_BRT, // 10
+		13,
+		OP_ABORT, // 12
+		OP_PUSH,  // 13
+		1005,
+		OP_BRF, // 15
+		18,
+		OP_ABORT, // 17
+		OP_EXIT,  // 18
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vm.sym[1005].TypeId != section.SymBoolId ||
+		vm.sym[1005].Value.(bool) != false {
+		t.Errorf("invalid bool %v", vm.sym[1005].Value)
+		return
+	}
+}
+
+func TestBoolIllegal(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1005,
+		OP_NOT, // not int
+	}
+
+	err := execute(prog, t)
+	if err == nil {
+		t.Error("expected type mismatch")
+		return
+	}
+}
+
+func TestDiv(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH,
+		1008,
+		OP_PUSH,
+		1007,
+		OP_PUSH,
+		1007,
+		OP_ADD,
+		OP_DIV, // 5 / 2
+		OP_POP,
+		1005,
+	}
+
+	vm, err := run(prog, t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vm.sym[1005].Value.(int) != 2 {
+		t.Errorf("invalid quotient %v", vm.sym[1005].Value)
+		return
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_PUSH, // 0
+		1006,    // 1 set 0
+		OP_POP,  // 2
+		1005,    // 3
+		OP_PUSH, // 4 loop body
+		1005,    // 5
+		OP_PUSH, // 6
+		1007,    // 7 add 1 to counter
+		OP_ADD,  // 8
+		OP_POP,  // 9
+		1005,    // 10
+		OP_PUSH, // 11
+		1005,    // 12
+		OP_PUSH, // 13
+		1008,    // 14
+		OP_LT,   // 15
+		OP_BRT,  // 16
+		4,       // 17 jump to loop body
+	}
+
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stop at the first backward jump
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = vm.RunContext(ctx)
+	se, ok := err.(*StopError)
+	if !ok || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected stop error, got %v", err)
+	}
+	if se.PC != 16 || vm.sym[1005].Value.(int) != 1 {
+		t.Fatalf("stopped at 0x%x with i %v", se.PC,
+			vm.sym[1005].Value)
+	}
+
+	// resume
+	err = vm.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vm.sym[1005].Value.(int) != 5 {
+		t.Fatalf("invalid counter %v", vm.sym[1005].Value)
+	}
+}
+
+func TestRunContextTimeout(t *testing.T) {
+	var prog []uint64 = []uint64{
+		OP_NOP, // 0
+		OP_JMP, // 1
+		0,      // 2 loop forever
+	}
+
+	i, err := newImage(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := New(i.GetImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	err = vm.RunContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}